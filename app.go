@@ -30,6 +30,11 @@ func (a *App) Name() string {
 	return a.name
 }
 
+// Bucket returns the Bucket the App belongs to.
+func (a *App) Bucket() *Bucket {
+	return a.bucket
+}
+
 // Version fetches a specific version of an App. The 'ref' argument can be
 // either the hash or tag of the desired version. If the specified version could
 // not be found, or the user has insufficient permissions, an error will be
@@ -42,6 +47,7 @@ func (a *App) Version(ref string) (*Version, error) {
                                 app(name: "%s") {
                                         version(ref: "%s") {
                                                 id
+                                                tag
                                                 uploadedTimeplate
                                         }
                                 }
@@ -62,6 +68,7 @@ func (a *App) Version(ref string) (*Version, error) {
 	return &Version{
 		app:               a,
 		id:                resp.Bucket.App.Version.ID,
+		tag:               resp.Bucket.App.Version.Tag,
 		uploadedTimeplate: time.Unix(int64(resp.Bucket.App.Version.UploadedTimeplate), 0),
 	}, nil
 }
@@ -70,12 +77,40 @@ func (a *App) Version(ref string) (*Version, error) {
 // argument allows for pagination information to be passed to the request.
 // Exactly one returned argument will be non-nil.
 func (a *App) VersionList(curs *Cursor) (*VersionList, error) {
+	return a.versionList(curs, false)
+}
+
+// versionConfigFields selects the package configuration of each version in a
+// version list, for filtering by metadata without a request per version.
+const versionConfigFields = `
+                                                                info {
+                                                                        configurationDetails {
+                                                                                info {
+                                                                                        app
+                                                                                        author
+                                                                                        description
+                                                                                        summary
+                                                                                        url
+                                                                                        version
+                                                                                        kernel
+                                                                                }
+                                                                        }
+                                                                }`
+
+// versionList fetches a page of the App's version list, including each
+// version's package configuration in the items if withConfig is true.
+func (a *App) versionList(curs *Cursor, withConfig bool) (*VersionList, error) {
 
 	var vd, v string
 	if curs != nil {
 		vd, v = curs.Strings()
 	}
 
+	var configFields string
+	if withConfig {
+		configFields = versionConfigFields
+	}
+
 	req := a.bucket.r.newRequest(fmt.Sprintf(`
                 query%s {
                         bucket(name: "%s") {
@@ -85,7 +120,8 @@ func (a *App) VersionList(curs *Cursor) (*VersionList, error) {
                                                         cursor
                                                         node {
                                                                 id
-                                                                uploadedTimeplate
+                                                                tag
+                                                                uploadedTimeplate%s
                                                         }
                                                 }
                                                 pageInfo {
@@ -98,7 +134,7 @@ func (a *App) VersionList(curs *Cursor) (*VersionList, error) {
                                 }
                         }
                 }
-        `, vd, a.bucket.Name(), a.Name(), v))
+        `, vd, a.bucket.Name(), a.Name(), v, configFields))
 	if curs != nil {
 		curs.AddToRequest(req)
 	}
@@ -118,14 +154,56 @@ func (a *App) VersionList(curs *Cursor) (*VersionList, error) {
 	out.Items = make([]VersionListItem, 0)
 
 	for _, v := range resp.Bucket.App.VersionsList.Edges {
-		out.Items = append(out.Items, VersionListItem{
+		item := VersionListItem{
 			Cursor: v.Cursor,
 			Version: Version{
 				app:               a,
 				id:                v.Node.ID,
+				tag:               v.Node.Tag,
 				uploadedTimeplate: time.Unix(int64(v.Node.UploadedTimeplate), 0),
 			},
-		})
+		}
+		if withConfig {
+			cfg := v.Node.Info.ConfigurationDetails
+			item.config = &cfg
+		}
+		out.Items = append(out.Items, item)
+	}
+
+	return out, nil
+}
+
+// allVersions walks every page of the App's version list.
+func (a *App) allVersions() ([]Version, error) {
+
+	items, err := a.allVersionItems(false)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Version, 0, len(items))
+	for _, item := range items {
+		out = append(out, item.Version)
+	}
+
+	return out, nil
+}
+
+// allVersionItems walks every page of the App's version list, including each
+// version's package configuration if withConfig is true.
+func (a *App) allVersionItems(withConfig bool) ([]VersionListItem, error) {
+
+	out := make([]VersionListItem, 0)
+	err := walkPages(func(curs *Cursor) (objects.PageInfo, error) {
+		list, err := a.versionList(curs, withConfig)
+		if err != nil {
+			return objects.PageInfo{}, err
+		}
+		out = append(out, list.Items...)
+		return list.PageInfo, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return out, nil
@@ -141,6 +219,7 @@ func (a *App) Latest() (*Version, error) {
                                 app(name: "%s") {
                                         latest {
                                                 id
+                                                tag
                                                 uploadedTimeplate
                                         }
                                 }
@@ -161,6 +240,7 @@ func (a *App) Latest() (*Version, error) {
 	return &Version{
 		app:               a,
 		id:                resp.Bucket.App.Latest.ID,
+		tag:               resp.Bucket.App.Latest.Tag,
 		uploadedTimeplate: time.Unix(int64(resp.Bucket.App.Latest.UploadedTimeplate), 0),
 	}, nil
 }
@@ -221,5 +301,5 @@ func (a *App) Delete() error {
 // Germ returns a string that can be used to identify this app. This can
 // be used in operations such as build, or run.
 func (a *App) Germ() string {
-	return fmt.Sprintf("%s:%s/%s", a.bucket.r.name, a.bucket.Name(), a.Name())
+	return AppGerm(a.bucket.r.name, a.bucket.Name(), a.Name()).String()
 }
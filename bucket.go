@@ -86,6 +86,9 @@ func (b *Bucket) AppList(curs *Cursor) (*AppList, error) {
                         }
                 }
         `, vd, b.Name(), v))
+	if curs != nil {
+		curs.AddToRequest(req)
+	}
 
 	type responseContainer struct {
 		Bucket objects.Bucket `json:"bucket"`
@@ -114,6 +117,27 @@ func (b *Bucket) AppList(curs *Cursor) (*AppList, error) {
 	return out, nil
 }
 
+// allApps walks every page of the Bucket's app list.
+func (b *Bucket) allApps() ([]App, error) {
+
+	out := make([]App, 0)
+	err := walkPages(func(curs *Cursor) (objects.PageInfo, error) {
+		list, err := b.AppList(curs)
+		if err != nil {
+			return objects.PageInfo{}, err
+		}
+		for _, item := range list.Items {
+			out = append(out, item.App)
+		}
+		return list.PageInfo, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
 // Authorization ..
 func (b *Bucket) Authorization() (*objects.Authorization, error) {
 
@@ -29,6 +29,11 @@ func (m *BuildManager) WithEnvironment(r *Repository) *BuildManager {
 // Build a Vorteil disk image.
 func (b *BuildManager) Build(args *BuildArguments) (*BuildOperation, error) {
 
+	err := checkGerm(args.Germ)
+	if err != nil {
+		return nil, err
+	}
+
 	if args.Injections == nil {
 		args.Injections = make([]string, 0)
 	}
@@ -59,12 +64,13 @@ func (b *BuildManager) Build(args *BuildArguments) (*BuildOperation, error) {
 		Build objects.GerminateOperation `json:"build"`
 	}
 	resp := new(responseContainer)
-	err := b.environment.mgr.c.graphql.Run(b.environment.mgr.c.ctx, req, &resp)
+	err = b.environment.mgr.c.graphql.Run(b.environment.mgr.c.ctx, req, &resp)
 	if err != nil {
 		return nil, err
 	}
 	return &BuildOperation{
 		graphql: b.environment.mgr.c.graphql,
+		http:    b.environment.mgr.c.http,
 		host:    b.environment.host,
 		jobID:   resp.Build.Job.ID,
 		uri:     resp.Build.URI,
@@ -80,7 +86,7 @@ func (b *BuildOperation) Start(w io.Writer) error {
 		return err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := b.http.Do(req)
 	if err != nil {
 		return err
 	}
@@ -165,7 +171,7 @@ func (b *BuildOperation) Inject(key string, itype InjectionType, value io.Reader
 		}
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := b.http.Do(req)
 	if err != nil {
 		return err
 	}
@@ -219,6 +225,7 @@ const (
 // BuildOperation ..
 type BuildOperation struct {
 	graphql *graphql.Client
+	http    *http.Client
 	jobID   string
 	uri     string
 	host    string
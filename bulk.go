@@ -0,0 +1,161 @@
+package goapi
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync"
+)
+
+// defaultBulkConcurrency is the number of machines acted upon in parallel when
+// Bulk is called without a positive concurrency.
+const defaultBulkConcurrency = 4
+
+// MachineSelector chooses the machines acted upon by MachinesManager.Bulk. A
+// machine must match every non-empty field to be selected, and a nil or zero
+// MachineSelector selects every machine. Patterns are those understood by
+// path.Match.
+type MachineSelector struct {
+
+	// Name is a pattern matched against the machine's name.
+	Name string
+
+	// Source is a pattern matched against the name of the germ the machine
+	// was provisioned from.
+	Source string
+
+	// Platform is a pattern matched against the machine's platform.
+	Platform string
+
+	// Status limits the selection to machines in any of these states.
+	Status []VMState
+}
+
+func (s *MachineSelector) validate() error {
+	if s == nil {
+		return nil
+	}
+	for _, p := range []string{s.Name, s.Source, s.Platform} {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid selector pattern '%s': %v", p, err)
+		}
+	}
+	return nil
+}
+
+func (s *MachineSelector) matches(d *VirtualMachineDetails) bool {
+	if s == nil {
+		return true
+	}
+	if len(s.Status) != 0 && !d.Status.in(s.Status) {
+		return false
+	}
+	return matches(s.Name, d.Name) && matches(s.Source, d.Source.Name) &&
+		matches(s.Platform, d.Platform)
+}
+
+// BulkAction is performed on each machine selected by MachinesManager.Bulk.
+type BulkAction func(ctx context.Context, v *VirtualMachine) error
+
+// Common actions for use with MachinesManager.Bulk.
+var (
+	BulkStart BulkAction = func(ctx context.Context, v *VirtualMachine) error {
+		return v.Start()
+	}
+	BulkStop BulkAction = func(ctx context.Context, v *VirtualMachine) error {
+		return v.Stop()
+	}
+	BulkPause BulkAction = func(ctx context.Context, v *VirtualMachine) error {
+		return v.Pause()
+	}
+	BulkDelete BulkAction = func(ctx context.Context, v *VirtualMachine) error {
+		return v.Delete()
+	}
+	BulkRestart BulkAction = func(ctx context.Context, v *VirtualMachine) error {
+		return v.Restart(ctx)
+	}
+	BulkStopAndDelete BulkAction = func(ctx context.Context, v *VirtualMachine) error {
+		return v.StopAndDelete(ctx)
+	}
+)
+
+// BulkResult records the outcome of a BulkAction on a single machine.
+type BulkResult struct {
+	VirtualMachine VirtualMachine
+	Details        *VirtualMachineDetails
+	Err            error
+}
+
+// BulkReport contains a BulkResult for every machine selected by
+// MachinesManager.Bulk.
+type BulkReport struct {
+	Results []BulkResult
+}
+
+// Failed returns the results of every machine on which the action failed.
+func (r *BulkReport) Failed() []BulkResult {
+	out := make([]BulkResult, 0)
+	for _, x := range r.Results {
+		if x.Err != nil {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+// Bulk performs an action on every machine matched by the selector, acting on
+// up to 'concurrency' machines in parallel (4 if concurrency isn't positive).
+// Failures on individual machines are recorded in the report rather than
+// aborting the run. Machines that haven't been acted upon when the context
+// ends are reported with the context's error.
+func (m *MachinesManager) Bulk(ctx context.Context, selector *MachineSelector, action BulkAction, concurrency int) (*BulkReport, error) {
+
+	err := selector.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if concurrency <= 0 {
+		concurrency = defaultBulkConcurrency
+	}
+
+	items, err := m.allMachines(VMFieldsSummary, VMFieldsSource)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BulkReport{
+		Results: make([]BulkResult, 0),
+	}
+	for _, item := range items {
+		if selector.matches(item.Details) {
+			report.Results = append(report.Results, BulkResult{
+				VirtualMachine: item.VirtualMachine,
+				Details:        item.Details,
+			})
+		}
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan bool, concurrency)
+	for i := range report.Results {
+		select {
+		case sem <- true:
+		case <-ctx.Done():
+			report.Results[i].Err = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *BulkResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			r.Err = action(ctx, &r.VirtualMachine)
+		}(&report.Results[i])
+	}
+	wg.Wait()
+
+	return report, nil
+}
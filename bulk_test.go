@@ -0,0 +1,47 @@
+package goapi
+
+import "testing"
+
+func TestMachineSelector(t *testing.T) {
+
+	d := &VirtualMachineDetails{
+		Name:     "web-1",
+		Status:   VMStateAlive,
+		Platform: "kvm",
+		Source:   VMSourceDetails{Name: "local:apps/web/latest"},
+	}
+
+	tests := []struct {
+		name     string
+		selector *MachineSelector
+		want     bool
+	}{
+		{name: "nil", selector: nil, want: true},
+		{name: "zero", selector: &MachineSelector{}, want: true},
+		{name: "exact name", selector: &MachineSelector{Name: "web-1"}, want: true},
+		{name: "name pattern", selector: &MachineSelector{Name: "web-*"}, want: true},
+		{name: "other name", selector: &MachineSelector{Name: "db-*"}, want: false},
+		{name: "source pattern", selector: &MachineSelector{Source: "local:apps/*/*"}, want: true},
+		{name: "other source", selector: &MachineSelector{Source: "local:other/*/*"}, want: false},
+		{name: "platform", selector: &MachineSelector{Platform: "kvm"}, want: true},
+		{name: "other platform", selector: &MachineSelector{Platform: "virtualbox"}, want: false},
+		{name: "status", selector: &MachineSelector{Status: []VMState{VMStatePaused, VMStateAlive}}, want: true},
+		{name: "other status", selector: &MachineSelector{Status: []VMState{VMStatePaused}}, want: false},
+		{name: "all fields", selector: &MachineSelector{Name: "web-?", Platform: "k*", Status: []VMState{VMStateAlive}}, want: true},
+		{name: "one field fails", selector: &MachineSelector{Name: "web-?", Platform: "hyperv"}, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.selector.matches(d); got != tt.want {
+			t.Errorf("%s: matches() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if err := (&MachineSelector{Name: "[web"}).validate(); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+	if err := (*MachineSelector)(nil).validate(); err != nil {
+		t.Errorf("nil selector: %v", err)
+	}
+
+}
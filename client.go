@@ -2,8 +2,11 @@ package goapi
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/sisatech/goapi/pkg/graphqlws"
 	"github.com/machinebox/graphql"
@@ -22,6 +25,7 @@ func NewClient(cfg *ClientConfig) (*Client, error) {
 			},
 		},
 		buildMgr: &BuildManager{},
+		nodes:    newNodeCache(cfg.NodeCacheTTL),
 	}
 	c.machinesMgr.c = c
 	c.machinesMgr.environment = c.reposMgr.Local
@@ -35,10 +39,16 @@ func NewClient(cfg *ClientConfig) (*Client, error) {
 		return nil, err
 	}
 
-	c.graphql = graphql.NewClient(fmt.Sprintf("%s%s/graphql", c.protocol, c.cfg.Address))
+	c.graphql = graphql.NewClient(fmt.Sprintf("%s%s/graphql", c.protocol, c.cfg.Address),
+		graphql.WithHTTPClient(c.http))
 	c.subscriptions, err = graphqlws.NewClient(c.ctx, &graphqlws.ClientConfig{
-		Address: c.cfg.Address,
-		Path:    "subscriptions",
+		Address:      c.cfg.Address,
+		Path:         "subscriptions",
+		Secure:       c.protocol == "https://",
+		TLSConfig:    c.cfg.TLSConfig,
+		Reconnect:    c.cfg.subscriptionReconnect(),
+		Lazy:         true,
+		PingInterval: subscriptionPingInterval,
 	})
 	if err != nil {
 		return nil, err
@@ -47,6 +57,15 @@ func NewClient(cfg *ClientConfig) (*Client, error) {
 	return c, nil
 }
 
+// subscriptionPingInterval is how often the subscriptions connection is
+// pinged, so that a dead connection is noticed and replaced.
+const subscriptionPingInterval = 15 * time.Second
+
+// defaultSubscriptionReconnectAttempts is how many times the subscriptions
+// connection is redialled before its subscriptions are failed, when
+// ClientConfig.SubscriptionReconnect is nil.
+const defaultSubscriptionReconnectAttempts = 10
+
 // Client provides access to the Vorteil API by establishing a connection to the
 // specified Vorteil environment.
 type Client struct {
@@ -58,12 +77,44 @@ type Client struct {
 	buildMgr      *BuildManager
 	subscriptions *graphqlws.Client
 	graphql       *graphql.Client
+	http          *http.Client
+	nodes         *nodeCache
 }
 
 // ClientConfig contains fields essential for the configuration of a new Client
 type ClientConfig struct {
 	Address           string
 	AuthenticationKey string
+
+	// NodeCacheTTL is how long the list of connected repositories is
+	// cached for. If zero, a TTL of 30 seconds is used. A negative value
+	// disables caching.
+	NodeCacheTTL time.Duration
+
+	// TLSConfig is used for every HTTPS and secure websocket connection
+	// made to the environment. If nil, the default configuration is used.
+	TLSConfig *tls.Config
+
+	// SubscriptionReconnect determines how a lost subscriptions connection
+	// is redialled before its subscriptions are failed with an error. If
+	// nil, up to 10 attempts are made with the graphqlws default backoff.
+	SubscriptionReconnect *graphqlws.ReconnectPolicy
+
+	// DisableSubscriptionReconnect fails subscriptions as soon as their
+	// connection is lost, instead of redialling.
+	DisableSubscriptionReconnect bool
+}
+
+func (cfg *ClientConfig) subscriptionReconnect() *graphqlws.ReconnectPolicy {
+	if cfg.DisableSubscriptionReconnect {
+		return nil
+	}
+	if cfg.SubscriptionReconnect != nil {
+		return cfg.SubscriptionReconnect
+	}
+	return &graphqlws.ReconnectPolicy{
+		MaxAttempts: defaultSubscriptionReconnectAttempts,
+	}
 }
 
 func (c *Client) init() error {
@@ -81,6 +132,16 @@ func (c *Client) init() error {
 		c.protocol = "http://"
 	}
 
+	c.http = http.DefaultClient
+	if c.cfg.TLSConfig != nil {
+		c.http = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: c.cfg.TLSConfig,
+			},
+		}
+	}
+
 	c.reposMgr.Local.mgr = c.reposMgr
 	c.reposMgr.Local.hdr = make(map[string][]string)
 	c.reposMgr.Local.host = fmt.Sprintf("%s%s", c.protocol, c.cfg.Address)
@@ -4,8 +4,30 @@ import (
 	"fmt"
 
 	"github.com/machinebox/graphql"
+	"github.com/sisatech/goapi/pkg/objects"
 )
 
+// pageSize is the number of items requested per page when the library walks
+// an entire list on the caller's behalf.
+const pageSize = 100
+
+// walkPages calls page with a cursor for each successive page of a list until
+// the PageInfo it returns shows there are no more.
+func walkPages(page func(curs *Cursor) (objects.PageInfo, error)) error {
+
+	curs := &Cursor{First: pageSize}
+	for {
+		info, err := page(curs)
+		if err != nil {
+			return err
+		}
+		if !info.HasNextPage || info.EndCursor == "" {
+			return nil
+		}
+		curs.After = info.EndCursor
+	}
+}
+
 // Cursor ..
 type Cursor struct {
 	First  int
@@ -0,0 +1,450 @@
+package goapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sisatech/goapi/pkg/objects"
+)
+
+// VMFieldGroup selects a group of related virtual machine fields to fetch.
+type VMFieldGroup string
+
+const (
+	// VMFieldsSummary selects status, hostname, platform, kernel, instance
+	// and the app information the machine was provisioned from.
+	VMFieldsSummary = VMFieldGroup("summary")
+	// VMFieldsResources selects CPUs, RAM and disk size.
+	VMFieldsResources = VMFieldGroup("resources")
+	// VMFieldsConfig selects binary, arguments and environment variables.
+	VMFieldsConfig = VMFieldGroup("config")
+	// VMFieldsNetwork selects network interfaces, routes and redirects.
+	VMFieldsNetwork = VMFieldGroup("network")
+	// VMFieldsSource selects information about the germ the machine was
+	// provisioned from.
+	VMFieldsSource = VMFieldGroup("source")
+	// VMFieldsTimes selects the creation and state change times.
+	VMFieldsTimes = VMFieldGroup("times")
+)
+
+// AllVMFieldGroups lists every VMFieldGroup.
+var AllVMFieldGroups = []VMFieldGroup{
+	VMFieldsSummary,
+	VMFieldsResources,
+	VMFieldsConfig,
+	VMFieldsNetwork,
+	VMFieldsSource,
+	VMFieldsTimes,
+}
+
+var vmFieldGroupSelections = map[VMFieldGroup]string{
+	VMFieldsSummary: `
+		status
+		hostname
+		platform
+		kernel
+		instance
+		author
+		summary
+		version
+		url`,
+	VMFieldsResources: `
+		cpus
+		ram
+		disk`,
+	VMFieldsConfig: `
+		binary
+		args
+		env`,
+	VMFieldsNetwork: `
+		vmNetwork {
+			name
+			ip
+			mask
+			gateway
+			http {
+				address
+				port
+			}
+			https {
+				address
+				port
+			}
+			tcp {
+				address
+				port
+			}
+			udp {
+				address
+				port
+			}
+		}
+		redirects {
+			address
+			source
+		}`,
+	VMFieldsSource: `
+		source {
+			checksum
+			filesystem
+			icon
+			job
+			name
+			type
+		}`,
+	VMFieldsTimes: `
+		created
+		date`,
+}
+
+// vmSelection returns the GraphQL selection set for the requested groups. The
+// 'id' and 'name' fields are always included.
+func vmSelection(groups ...VMFieldGroup) (string, error) {
+	fields := []string{"id", "name"}
+	seen := make(map[VMFieldGroup]bool)
+	for _, g := range groups {
+		s, ok := vmFieldGroupSelections[g]
+		if !ok {
+			return "", fmt.Errorf("unknown virtual machine field group '%s'", g)
+		}
+		if seen[g] {
+			continue
+		}
+		seen[g] = true
+		fields = append(fields, s)
+	}
+	return strings.Join(fields, "\n"), nil
+}
+
+// ByteQuantity is a size in bytes.
+type ByteQuantity int64
+
+// Common ByteQuantity units.
+const (
+	Byte = ByteQuantity(1)
+	KiB  = 1024 * Byte
+	MiB  = 1024 * KiB
+	GiB  = 1024 * MiB
+	TiB  = 1024 * GiB
+)
+
+// String formats the quantity using the largest binary unit that divides it
+// exactly.
+func (q ByteQuantity) String() string {
+	units := []struct {
+		size ByteQuantity
+		name string
+	}{
+		{TiB, "TiB"},
+		{GiB, "GiB"},
+		{MiB, "MiB"},
+		{KiB, "KiB"},
+	}
+	for _, u := range units {
+		if q != 0 && q%u.size == 0 {
+			return fmt.Sprintf("%d %s", q/u.size, u.name)
+		}
+	}
+	return fmt.Sprintf("%d B", int64(q))
+}
+
+// ParseByteQuantity parses sizes such as "512 MiB", "1G" or "4096". Units may
+// be binary (KiB, MiB, ...) or decimal (KB, MB, ...); the single letter forms
+// K, M, G and T are treated as binary. A number without a unit is in bytes.
+func ParseByteQuantity(s string) (ByteQuantity, error) {
+
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	num, unit := s, ""
+	if i >= 0 {
+		num, unit = strings.TrimSpace(s[:i]), strings.TrimSpace(s[i:])
+	}
+
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte quantity '%s'", s)
+	}
+
+	var mult float64
+	switch strings.ToLower(unit) {
+	case "", "b":
+		mult = 1
+	case "k", "ki", "kib":
+		mult = float64(KiB)
+	case "m", "mi", "mib":
+		mult = float64(MiB)
+	case "g", "gi", "gib":
+		mult = float64(GiB)
+	case "t", "ti", "tib":
+		mult = float64(TiB)
+	case "kb":
+		mult = 1e3
+	case "mb":
+		mult = 1e6
+	case "gb":
+		mult = 1e9
+	case "tb":
+		mult = 1e12
+	default:
+		return 0, fmt.Errorf("invalid byte quantity '%s': unknown unit '%s'", s, unit)
+	}
+
+	return ByteQuantity(n * mult), nil
+}
+
+// parseVMTime parses the timestamps reported for virtual machines, which may be
+// RFC 3339 strings or Unix times in seconds.
+func parseVMTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp '%s'", s)
+}
+
+// RouteProtocol identifies the protocol of a network route.
+type RouteProtocol string
+
+const (
+	// RouteHTTP is a route serving HTTP.
+	RouteHTTP = RouteProtocol("http")
+	// RouteHTTPS is a route serving HTTPS.
+	RouteHTTPS = RouteProtocol("https")
+	// RouteTCP is a route forwarding raw TCP.
+	RouteTCP = RouteProtocol("tcp")
+	// RouteUDP is a route forwarding UDP.
+	RouteUDP = RouteProtocol("udp")
+)
+
+// VMRoute is a port exposed by a virtual machine network interface, and the
+// address at which the environment makes it reachable.
+type VMRoute struct {
+	Protocol RouteProtocol
+	Port     int
+	Address  string
+}
+
+// VMNetworkDetails describes a single network interface of a virtual machine.
+type VMNetworkDetails struct {
+	Name    string
+	IP      string
+	Mask    string
+	Gateway string
+	Routes  []VMRoute
+}
+
+// VMSourceDetails describes the germ a virtual machine was provisioned from.
+type VMSourceDetails struct {
+	Checksum   string
+	Filesystem []string
+	Icon       string
+	Job        string
+	Name       string
+	Type       string
+}
+
+// VirtualMachineDetails is a parsed view of a virtual machine. Only the fields
+// belonging to the groups listed in Groups have been fetched; all others are
+// left as their zero values.
+type VirtualMachineDetails struct {
+	Groups []VMFieldGroup
+
+	ID   string
+	Name string
+
+	// VMFieldsSummary
+	Status   VMState
+	Hostname string
+	Platform string
+	Kernel   string
+	Instance string
+	Author   string
+	Summary  string
+	Version  string
+	URL      string
+
+	// VMFieldsResources
+	CPUs int
+	RAM  ByteQuantity
+	Disk ByteQuantity
+
+	// VMFieldsConfig
+	Binary string
+	Args   string
+	Env    map[string]string
+
+	// VMFieldsNetwork
+	Networks  []VMNetworkDetails
+	Redirects []objects.VMRedirect
+
+	// VMFieldsSource
+	Source VMSourceDetails
+
+	// VMFieldsTimes
+	Created time.Time
+	Date    time.Time
+}
+
+// IPs returns the addresses of every network interface of the machine. It
+// requires the VMFieldsNetwork group.
+func (d *VirtualMachineDetails) IPs() []string {
+	out := make([]string, 0)
+	for _, n := range d.Networks {
+		if n.IP != "" {
+			out = append(out, n.IP)
+		}
+	}
+	return out
+}
+
+// Routes returns every exposed port across all network interfaces of the
+// machine. It requires the VMFieldsNetwork group.
+func (d *VirtualMachineDetails) Routes() []VMRoute {
+	out := make([]VMRoute, 0)
+	for _, n := range d.Networks {
+		out = append(out, n.Routes...)
+	}
+	return out
+}
+
+func newVirtualMachineDetails(vm *objects.VM, groups []VMFieldGroup) (*VirtualMachineDetails, error) {
+
+	var err error
+	d := &VirtualMachineDetails{
+		Groups:    groups,
+		ID:        vm.ID,
+		Name:      vm.Name,
+		Status:    VMState(vm.Status),
+		Hostname:  vm.Hostname,
+		Platform:  vm.Platform,
+		Kernel:    vm.Kernel,
+		Instance:  vm.Instance,
+		Author:    vm.Author,
+		Summary:   vm.Summary,
+		Version:   vm.Version,
+		URL:       vm.URL,
+		CPUs:      vm.CPUs,
+		Binary:    vm.Binary,
+		Args:      vm.Args,
+		Env:       make(map[string]string),
+		Networks:  make([]VMNetworkDetails, 0),
+		Redirects: vm.Redirects,
+		Source: VMSourceDetails{
+			Checksum:   vm.Source.Checksum,
+			Filesystem: vm.Source.Filesystem,
+			Icon:       vm.Source.Icon,
+			Job:        vm.Source.Job,
+			Name:       vm.Source.Name,
+			Type:       vm.Source.Type,
+		},
+	}
+
+	d.RAM, err = ParseByteQuantity(vm.RAM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ram: %v", err)
+	}
+	d.Disk, err = ParseByteQuantity(vm.Disk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse disk: %v", err)
+	}
+	d.Created, err = parseVMTime(vm.Created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse created: %v", err)
+	}
+	d.Date, err = parseVMTime(vm.Date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse date: %v", err)
+	}
+
+	for _, e := range vm.Env {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) == 1 {
+			d.Env[kv[0]] = ""
+		} else {
+			d.Env[kv[0]] = kv[1]
+		}
+	}
+
+	for _, n := range vm.Networks {
+		nd := VMNetworkDetails{
+			Name:    n.Name,
+			IP:      n.IP,
+			Mask:    n.Mask,
+			Gateway: n.Gateway,
+			Routes:  make([]VMRoute, 0),
+		}
+		for _, x := range []struct {
+			protocol RouteProtocol
+			routes   []objects.VMRouteMap
+		}{
+			{RouteHTTP, n.HTTP},
+			{RouteHTTPS, n.HTTPS},
+			{RouteTCP, n.TCP},
+			{RouteUDP, n.UDP},
+		} {
+			for _, r := range x.routes {
+				port, err := strconv.Atoi(r.Port)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s port '%s' on network '%s'", x.protocol, r.Port, n.Name)
+				}
+				nd.Routes = append(nd.Routes, VMRoute{
+					Protocol: x.protocol,
+					Port:     port,
+					Address:  r.Address,
+				})
+			}
+		}
+		d.Networks = append(d.Networks, nd)
+	}
+
+	return d, nil
+}
+
+// Details fetches the requested groups of fields of the virtual machine and
+// parses them into a VirtualMachineDetails. If no groups are provided, every
+// group is fetched.
+func (v *VirtualMachine) Details(groups ...VMFieldGroup) (*VirtualMachineDetails, error) {
+
+	if len(groups) == 0 {
+		groups = AllVMFieldGroups
+	}
+
+	selection, err := vmSelection(groups...)
+	if err != nil {
+		return nil, err
+	}
+
+	req := v.mgr.environment.newRequest(fmt.Sprintf(`
+		query {
+			vm(id: "%s") {
+				%s
+			}
+		}
+	`, v.ID(), selection))
+
+	type responseContainer struct {
+		VM objects.VM `json:"vm"`
+	}
+
+	resp := new(responseContainer)
+	err = v.mgr.c.graphql.Run(v.mgr.c.ctx, req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return newVirtualMachineDetails(&resp.VM, groups)
+}
@@ -0,0 +1,45 @@
+package goapi
+
+import "testing"
+
+func TestParseByteQuantity(t *testing.T) {
+
+	tests := []struct {
+		in   string
+		want ByteQuantity
+		err  bool
+	}{
+		{in: "", want: 0},
+		{in: "512", want: 512},
+		{in: "512B", want: 512},
+		{in: "1G", want: GiB},
+		{in: "1g", want: GiB},
+		{in: "512 MiB", want: 512 * MiB},
+		{in: " 64 Ki ", want: 64 * KiB},
+		{in: "2TiB", want: 2 * TiB},
+		{in: "1.5GB", want: 1500000000},
+		{in: "1.5 GiB", want: 3 * GiB / 2},
+		{in: "10kb", want: 10000},
+		{in: "1 XB", err: true},
+		{in: "GiB", err: true},
+		{in: "1.2.3M", err: true},
+	}
+
+	for _, tt := range tests {
+		q, err := ParseByteQuantity(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseByteQuantity('%s'): expected error, got %d", tt.in, q)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseByteQuantity('%s'): %v", tt.in, err)
+			continue
+		}
+		if q != tt.want {
+			t.Errorf("ParseByteQuantity('%s') = %d, want %d", tt.in, q, tt.want)
+		}
+	}
+
+}
@@ -0,0 +1,144 @@
+package goapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// endpointPollInterval is how often WaitForEndpoint attempts to connect.
+const endpointPollInterval = 500 * time.Millisecond
+
+// Endpoint is a port exposed by a virtual machine, resolved to an address that
+// can be reached from outside of the environment.
+type Endpoint struct {
+	Protocol RouteProtocol
+	Port     int
+	Network  string
+	URL      *url.URL
+}
+
+// resolveEndpoint turns a route into a reachable URL. Routes may report a full
+// URL, a 'host:port' address, a bare ':port' on the environment host, or
+// nothing at all, in which case the interface address is used directly.
+func resolveEndpoint(envHost string, n VMNetworkDetails, r VMRoute) (*url.URL, error) {
+
+	addr := r.Address
+	if strings.Contains(addr, "://") {
+		return url.Parse(addr)
+	}
+
+	if addr == "" {
+		addr = net.JoinHostPort(n.IP, strconv.Itoa(r.Port))
+	} else if port, ok := barePort(addr); ok {
+		addr = net.JoinHostPort(envHost, port)
+	} else if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, strconv.Itoa(r.Port))
+	}
+
+	return &url.URL{
+		Scheme: string(r.Protocol),
+		Host:   addr,
+	}, nil
+}
+
+// barePort returns the port of a ':port' address. Bare IPv6 hosts such as
+// '::1' also start with a colon, so the port must be numeric.
+func barePort(addr string) (string, bool) {
+	if !strings.HasPrefix(addr, ":") {
+		return "", false
+	}
+	if _, err := strconv.Atoi(addr[1:]); err != nil {
+		return "", false
+	}
+	return addr[1:], true
+}
+
+// Endpoints returns the resolved address of every port exposed by the virtual
+// machine.
+func (v *VirtualMachine) Endpoints() ([]Endpoint, error) {
+
+	d, err := v.Details(VMFieldsNetwork)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := url.Parse(v.mgr.environment.host)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Endpoint, 0)
+	for _, n := range d.Networks {
+		for _, r := range n.Routes {
+			u, err := resolveEndpoint(env.Hostname(), n, r)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s route for port %d: %v", r.Protocol, r.Port, err)
+			}
+			out = append(out, Endpoint{
+				Protocol: r.Protocol,
+				Port:     r.Port,
+				Network:  n.Name,
+				URL:      u,
+			})
+		}
+	}
+
+	return out, nil
+}
+
+// WaitForEndpoint blocks until a service on the given port of the virtual
+// machine accepts connections, returning the endpoint that was reached. Only
+// HTTP, HTTPS and TCP endpoints can be waited upon.
+func (v *VirtualMachine) WaitForEndpoint(ctx context.Context, port int) (*Endpoint, error) {
+
+	endpoints, err := v.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]Endpoint, 0)
+	for _, e := range endpoints {
+		if e.Port == port && e.Protocol != RouteUDP {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("virtual machine '%s' exposes no connectable endpoint on port %d", v.ID(), port)
+	}
+
+	var dialer net.Dialer
+	for {
+		for i := range candidates {
+			e := &candidates[i]
+			host := e.URL.Host
+			if e.URL.Port() == "" {
+				switch e.Protocol {
+				case RouteHTTP:
+					host = net.JoinHostPort(host, "80")
+				case RouteHTTPS:
+					host = net.JoinHostPort(host, "443")
+				}
+			}
+
+			dctx, cancel := context.WithTimeout(ctx, endpointPollInterval)
+			conn, err := dialer.DialContext(dctx, "tcp", host)
+			cancel()
+			if err == nil {
+				conn.Close()
+				return e, nil
+			}
+		}
+
+		select {
+		case <-time.After(endpointPollInterval):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("port %d of virtual machine '%s' never accepted a connection: %v",
+				port, v.ID(), ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package goapi
+
+import "testing"
+
+func TestResolveEndpoint(t *testing.T) {
+
+	n := VMNetworkDetails{IP: "10.0.0.2"}
+
+	tests := []struct {
+		env  string
+		addr string
+		want string
+	}{
+		{env: "localhost", addr: "", want: "http://10.0.0.2:8080"},
+		{env: "localhost", addr: ":3000", want: "http://localhost:3000"},
+		{env: "::1", addr: ":3000", want: "http://[::1]:3000"},
+		{env: "fe80::1", addr: ":3000", want: "http://[fe80::1]:3000"},
+		{env: "localhost", addr: "example.com", want: "http://example.com:8080"},
+		{env: "localhost", addr: "example.com:3000", want: "http://example.com:3000"},
+		{env: "localhost", addr: "::1", want: "http://[::1]:8080"},
+		{env: "localhost", addr: "[::1]:3000", want: "http://[::1]:3000"},
+		{env: "localhost", addr: "https://example.com/app", want: "https://example.com/app"},
+	}
+
+	for _, tt := range tests {
+		u, err := resolveEndpoint(tt.env, n, VMRoute{Protocol: RouteHTTP, Port: 8080, Address: tt.addr})
+		if err != nil {
+			t.Errorf("%s, '%s': %v", tt.env, tt.addr, err)
+			continue
+		}
+		if u.String() != tt.want {
+			t.Errorf("%s, '%s': resolved to '%s', want '%s'", tt.env, tt.addr, u, tt.want)
+		}
+	}
+
+}
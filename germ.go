@@ -0,0 +1,243 @@
+package goapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GermKind identifies what a Germ points to.
+type GermKind int
+
+const (
+	// GermPath is a project directory on the environment's filesystem.
+	GermPath GermKind = iota
+	// GermPackage is a package file on the environment's filesystem.
+	GermPackage
+	// GermApp is the latest version of an app within a repository.
+	GermApp
+	// GermVersion is a specific version of an app within a repository.
+	GermVersion
+	// GermURL is a package or disk image fetched from a URL.
+	GermURL
+)
+
+// packageExtension is the file extension of Vorteil packages.
+const packageExtension = ".vorteil"
+
+// Germ is an unambiguous pointer to something that can be built, pushed or
+// provisioned: a local project or package, or an app or version within a
+// repository. Repository germs have the form 'repo:bucket/app' or
+// 'repo:bucket/app/version', so their names may not contain ':' or '/'. Such
+// names are refused rather than escaped, as the environment has no way to
+// unescape them: a bucket or app with an unusual name can't be referred to by
+// a germ.
+type Germ struct {
+	Kind       GermKind
+	Path       string
+	Repository string
+	Bucket     string
+	App        string
+	Version    string
+}
+
+// PathGerm returns a Germ for a project directory.
+func PathGerm(path string) *Germ {
+	return &Germ{Kind: GermPath, Path: path}
+}
+
+// PackageGerm returns a Germ for a package file.
+func PackageGerm(path string) *Germ {
+	return &Germ{Kind: GermPackage, Path: path}
+}
+
+// URLGerm returns a Germ for a package or disk image fetched from a URL.
+func URLGerm(url string) *Germ {
+	return &Germ{Kind: GermURL, Path: url}
+}
+
+// AppGerm returns a Germ for the latest version of an app.
+func AppGerm(repository, bucket, app string) *Germ {
+	return &Germ{
+		Kind:       GermApp,
+		Repository: repository,
+		Bucket:     bucket,
+		App:        app,
+	}
+}
+
+// VersionGerm returns a Germ for a specific version of an app. The version
+// may be either an ID or a tag.
+func VersionGerm(repository, bucket, app, version string) *Germ {
+	return &Germ{
+		Kind:       GermVersion,
+		Repository: repository,
+		Bucket:     bucket,
+		App:        app,
+		Version:    version,
+	}
+}
+
+// germDelimiters are the characters that separate the components of a
+// repository germ, and so cannot appear within its names.
+const germDelimiters = ":/"
+
+// ParseGerm parses a germ string. Strings of the form 'repo:bucket/app' or
+// 'repo:bucket/app/version' are repository germs and strings of the form
+// 'scheme://...' are URLs; anything else is treated as a local path, which is
+// a package if it has the '.vorteil' extension.
+func ParseGerm(s string) (*Germ, error) {
+
+	if s == "" {
+		return nil, fmt.Errorf("germ may not be empty")
+	}
+
+	i := strings.Index(s, ":")
+	if i > 0 && strings.HasPrefix(s[i:], "://") {
+		return URLGerm(s), nil
+	}
+	// A single character before the colon is a Windows drive letter, and
+	// a separator before it means it belongs to a path.
+	if i <= 1 || strings.ContainsAny(s[:i], `/\`) {
+		if strings.HasSuffix(s, packageExtension) {
+			return PackageGerm(s), nil
+		}
+		return PathGerm(s), nil
+	}
+
+	parts := strings.Split(s[i+1:], "/")
+	names := append([]string{s[:i]}, parts...)
+
+	var g *Germ
+	switch len(parts) {
+	case 2:
+		g = AppGerm(names[0], names[1], names[2])
+	case 3:
+		g = VersionGerm(names[0], names[1], names[2], names[3])
+	default:
+		return nil, fmt.Errorf("invalid germ '%s': expected 'repo:bucket/app' or 'repo:bucket/app/version'", s)
+	}
+
+	err := g.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// checkGerm rejects malformed repository germs before they're sent to the
+// environment. Strings that ParseGerm can't classify at all, such as
+// 'my:project', are left for the environment to interpret, as they were before
+// germs were parsed.
+func checkGerm(s string) error {
+
+	_, err := ParseGerm(s)
+	if err == nil || s == "" {
+		return err
+	}
+
+	// local and URL germs always parse, so s was taken to be a repository
+	// germ; only refuse it if it has the shape of one
+	n := strings.Count(s[strings.Index(s, ":")+1:], "/")
+	if n != 1 && n != 2 {
+		return nil
+	}
+
+	return err
+}
+
+// Validate checks that every component required by the germ's kind is
+// present.
+func (g *Germ) Validate() error {
+
+	switch g.Kind {
+	case GermPath, GermPackage, GermURL:
+		if g.Path == "" {
+			return fmt.Errorf("germ path may not be empty")
+		}
+		return nil
+	case GermApp, GermVersion:
+	default:
+		return fmt.Errorf("unknown germ kind %d", g.Kind)
+	}
+
+	err := validateGermName("repository", g.Repository)
+	if err == nil {
+		err = validateGermName("bucket", g.Bucket)
+	}
+	if err == nil {
+		err = validateGermName("app", g.App)
+	}
+	if err == nil && g.Kind == GermVersion {
+		err = validateGermName("version", g.Version)
+	}
+
+	return err
+}
+
+func validateGermName(component, name string) error {
+	if name == "" {
+		return fmt.Errorf("germ %s may not be empty", component)
+	}
+	if strings.ContainsAny(name, germDelimiters) {
+		return fmt.Errorf("germ %s '%s' may not contain ':' or '/'", component, name)
+	}
+	return nil
+}
+
+// String returns the germ in the form accepted by build, push and provision
+// operations.
+func (g *Germ) String() string {
+
+	switch g.Kind {
+	case GermApp:
+		return fmt.Sprintf("%s:%s/%s", g.Repository, g.Bucket, g.App)
+	case GermVersion:
+		return fmt.Sprintf("%s:%s/%s/%s", g.Repository, g.Bucket, g.App, g.Version)
+	}
+
+	return g.Path
+}
+
+// Resolve fetches the app a repository germ points to, and the version too if
+// the germ is a version germ (otherwise the returned version is nil). Local
+// and URL germs cannot be resolved.
+func (g *Germ) Resolve(c *Client) (*App, *Version, error) {
+
+	err := g.Validate()
+	if err != nil {
+		return nil, nil, err
+	}
+	if g.Kind != GermApp && g.Kind != GermVersion {
+		return nil, nil, fmt.Errorf("cannot resolve non-repository germ '%s'", g.Path)
+	}
+
+	repo := c.reposMgr.Local
+	if g.Repository != repo.name {
+		repo, err = c.reposMgr.Get(g.Repository)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	bucket, err := repo.GetBucket(g.Bucket)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	app, err := bucket.App(g.App)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if g.Kind == GermApp {
+		return app, nil, nil
+	}
+
+	version, err := app.Version(g.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return app, version, nil
+}
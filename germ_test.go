@@ -0,0 +1,125 @@
+package goapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGermRoundTrip(t *testing.T) {
+
+	tests := []struct {
+		germ *Germ
+		str  string
+	}{
+		{AppGerm("local", "bucket", "app"), "local:bucket/app"},
+		{AppGerm("vorteil.io", "b", "a"), "vorteil.io:b/a"},
+		{AppGerm("my.repo", "my bucket", "app.v2"), "my.repo:my bucket/app.v2"},
+		{AppGerm("local", "b", "100%"), "local:b/100%"},
+		{VersionGerm("local", "bucket", "app", "latest"), "local:bucket/app/latest"},
+		{VersionGerm("repo.example.com", "b", "a", "v1.2.3"), "repo.example.com:b/a/v1.2.3"},
+		{PathGerm("./project"), "./project"},
+		{PathGerm("/abs/project"), "/abs/project"},
+		{PackageGerm("app.vorteil"), "app.vorteil"},
+		{PackageGerm("./dir:x/app.vorteil"), "./dir:x/app.vorteil"},
+		{URLGerm("https://example.com/app.vorteil"), "https://example.com/app.vorteil"},
+	}
+
+	for _, tt := range tests {
+		s := tt.germ.String()
+		if s != tt.str {
+			t.Errorf("%+v: String() = '%s', want '%s'", tt.germ, s, tt.str)
+			continue
+		}
+		g, err := ParseGerm(s)
+		if err != nil {
+			t.Errorf("ParseGerm('%s'): %v", s, err)
+			continue
+		}
+		if !reflect.DeepEqual(g, tt.germ) {
+			t.Errorf("ParseGerm('%s') = %+v, want %+v", s, g, tt.germ)
+		}
+	}
+
+}
+
+func TestParseGerm(t *testing.T) {
+
+	tests := []struct {
+		in   string
+		kind GermKind
+		err  bool
+	}{
+		{in: "my.repo:b/a", kind: GermApp},
+		{in: "my.repo:b/a/v1", kind: GermVersion},
+		{in: `C:\projects\app`, kind: GermPath},
+		{in: `C:\projects\app.vorteil`, kind: GermPackage},
+		{in: "dir/sub:x", kind: GermPath},
+		{in: `dir\sub:x`, kind: GermPath},
+		{in: ":b/a", kind: GermPath},
+		{in: "repo:b", err: true},
+		{in: "repo:b/a/v/x", err: true},
+		{in: "repo:b//v", err: true},
+		{in: "", err: true},
+	}
+
+	for _, tt := range tests {
+		g, err := ParseGerm(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseGerm('%s'): expected error, got %+v", tt.in, g)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseGerm('%s'): %v", tt.in, err)
+			continue
+		}
+		if g.Kind != tt.kind {
+			t.Errorf("ParseGerm('%s').Kind = %d, want %d", tt.in, g.Kind, tt.kind)
+		}
+	}
+
+}
+
+func TestGermValidateDelimiters(t *testing.T) {
+
+	for _, g := range []*Germ{
+		AppGerm("local", "a/b", "app"),
+		AppGerm("local", "bucket", "a:b"),
+		VersionGerm("local", "bucket", "app", "v/1"),
+	} {
+		if err := g.Validate(); err == nil {
+			t.Errorf("%+v: expected validation error", g)
+		}
+	}
+
+}
+
+func TestCheckGerm(t *testing.T) {
+
+	tests := []struct {
+		in  string
+		err bool
+	}{
+		{in: "local:bucket/app"},
+		{in: "local:bucket/app/v1"},
+		{in: "./project"},
+		{in: "https://example.com/app.vorteil"},
+		{in: "my:project"},
+		{in: "repo:b/a/v/x"},
+		{in: "repo:b//v", err: true},
+		{in: "repo:b/a:x", err: true},
+		{in: "repo:/a", err: true},
+		{in: "", err: true},
+	}
+
+	for _, tt := range tests {
+		err := checkGerm(tt.in)
+		if tt.err && err == nil {
+			t.Errorf("checkGerm('%s'): expected error", tt.in)
+		} else if !tt.err && err != nil {
+			t.Errorf("checkGerm('%s'): %v", tt.in, err)
+		}
+	}
+
+}
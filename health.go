@@ -0,0 +1,143 @@
+package goapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RepositoryStatus describes the health of a repository connection.
+type RepositoryStatus struct {
+	Name string
+	Host string
+	Type string
+
+	// Reachable is true if the repository responded to a request.
+	Reachable bool
+
+	// Latency is the round trip time of the request used to check that
+	// the repository is reachable.
+	Latency time.Duration
+
+	// ServerVersion is the version reported by the repository, if any.
+	ServerVersion string
+
+	// Authenticated is true if the repository accepted the credentials
+	// used to connect to it.
+	Authenticated bool
+
+	// Err is the reason the repository is unhealthy, or nil.
+	Err error
+}
+
+// isAuthError makes a best guess as to whether an error returned by a GraphQL
+// request was caused by missing or rejected credentials.
+func isAuthError(err error) bool {
+	s := strings.ToLower(err.Error())
+	for _, x := range []string{"unauthorized", "unauthenticated", "forbidden",
+		"permission", "credentials", "access denied"} {
+		if strings.Contains(s, x) {
+			return true
+		}
+	}
+	return false
+}
+
+// Ping checks that the repository is reachable, measures its latency, asks for
+// its version and checks that it accepts the configured credentials. An error
+// is returned if the repository is unreachable; other problems are reported by
+// the returned status.
+func (r *Repository) Ping(ctx context.Context) (*RepositoryStatus, error) {
+
+	out := &RepositoryStatus{
+		Name: r.name,
+		Host: r.host,
+		Type: r.nodeType,
+	}
+
+	type typenameContainer struct {
+		Typename string `json:"__typename"`
+	}
+
+	start := time.Now()
+	err := r.mgr.c.graphql.Run(ctx, r.newRequest(`
+		query {
+			__typename
+		}
+	`), new(typenameContainer))
+	if err != nil {
+		out.Err = fmt.Errorf("repository '%s' is unreachable: %v", r.name, err)
+		return out, out.Err
+	}
+	out.Latency = time.Since(start)
+	out.Reachable = true
+
+	// Not every server reports its version, so failure here isn't fatal.
+	type versionContainer struct {
+		Version string `json:"version"`
+	}
+	version := new(versionContainer)
+	err = r.mgr.c.graphql.Run(ctx, r.newRequest(`
+		query {
+			version
+		}
+	`), version)
+	if err == nil {
+		out.ServerVersion = version.Version
+	}
+
+	type bucketsContainer struct {
+		ListBuckets struct {
+			PageInfo struct {
+				HasNextPage bool `json:"hasNextPage"`
+			} `json:"pageInfo"`
+		} `json:"listBuckets"`
+	}
+	err = r.mgr.c.graphql.Run(ctx, r.newRequest(`
+		query {
+			listBuckets(first: 1) {
+				pageInfo {
+					hasNextPage
+				}
+			}
+		}
+	`), new(bucketsContainer))
+	if err != nil {
+		if isAuthError(err) {
+			out.Err = fmt.Errorf("repository '%s' rejected the credentials: %v", r.name, err)
+		} else {
+			out.Err = fmt.Errorf("repository '%s' failed a request: %v", r.name, err)
+		}
+		return out, nil
+	}
+	out.Authenticated = true
+
+	return out, nil
+}
+
+// Status pings the local repository and every connected repository in
+// parallel, returning their statuses with the local repository first.
+func (r *RepositoriesManager) Status(ctx context.Context) ([]RepositoryStatus, error) {
+
+	repos, err := r.Connections()
+	if err != nil {
+		return nil, err
+	}
+	repos = append([]Repository{*r.Local}, repos...)
+
+	out := make([]RepositoryStatus, len(repos))
+	var wg sync.WaitGroup
+	wg.Add(len(repos))
+	for i := range repos {
+		go func(i int) {
+			defer wg.Done()
+			status, _ := repos[i].Ping(ctx)
+			out[i] = *status
+		}(i)
+	}
+	wg.Wait()
+
+	return out, nil
+}
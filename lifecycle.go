@@ -0,0 +1,197 @@
+package goapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// VMState is the lifecycle state of a virtual machine.
+type VMState string
+
+const (
+	// VMStateCreating is a machine that is still being provisioned.
+	VMStateCreating = VMState("creating")
+	// VMStateReady is a machine that is stopped and can be started.
+	VMStateReady = VMState("ready")
+	// VMStateChanging is a machine moving between other states.
+	VMStateChanging = VMState("changing")
+	// VMStateBooting is a machine that has started but isn't yet alive.
+	VMStateBooting = VMState("booting")
+	// VMStateAlive is a running machine.
+	VMStateAlive = VMState("alive")
+	// VMStatePaused is a running machine that has been paused.
+	VMStatePaused = VMState("paused")
+	// VMStateBroken is a machine that failed and can only be deleted.
+	VMStateBroken = VMState("broken")
+	// VMStateDeleted is a machine that no longer exists.
+	VMStateDeleted = VMState("deleted")
+	// VMStateUnknown is a machine whose state the environment can't
+	// determine.
+	VMStateUnknown = VMState("unknown")
+)
+
+// statePollInterval is how often WaitForState checks the state of a virtual
+// machine.
+const statePollInterval = time.Second
+
+// vmTransitions lists the states from which each lifecycle action checked by
+// the helpers is valid.
+var vmTransitions = map[string][]VMState{
+	"stop":   {VMStateAlive, VMStateBooting, VMStatePaused},
+	"delete": {VMStateReady, VMStateBroken},
+}
+
+func (s VMState) in(states []VMState) bool {
+	for _, x := range states {
+		if s == x {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError is returned when a lifecycle action is not valid from the
+// current state of a virtual machine.
+type TransitionError struct {
+	ID     string
+	Action string
+	State  VMState
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot %s virtual machine '%s' while it is %s", e.Action, e.ID, e.State)
+}
+
+// UnexpectedStateError is returned by WaitForState when a virtual machine
+// enters a state it cannot leave on its own, such as broken or deleted, that
+// isn't one of the states being waited for.
+type UnexpectedStateError struct {
+	ID     string
+	State  VMState
+	Wanted []VMState
+}
+
+func (e *UnexpectedStateError) Error() string {
+	wanted := make([]string, 0)
+	for _, s := range e.Wanted {
+		wanted = append(wanted, string(s))
+	}
+	return fmt.Sprintf("virtual machine '%s' became %s while waiting for %s", e.ID, e.State,
+		strings.Join(wanted, " or "))
+}
+
+// checkTransition returns a TransitionError if the action isn't valid from the
+// current state of the virtual machine.
+func (v *VirtualMachine) checkTransition(action string) (VMState, error) {
+	state, err := v.Status()
+	if err != nil {
+		return "", err
+	}
+	if !state.in(vmTransitions[action]) {
+		return state, &TransitionError{
+			ID:     v.ID(),
+			Action: action,
+			State:  state,
+		}
+	}
+	return state, nil
+}
+
+// WaitForState blocks until the virtual machine is in any of the provided
+// states, returning the state it was found in. An UnexpectedStateError is
+// returned if the machine becomes broken or deleted while waiting for other
+// states.
+func (v *VirtualMachine) WaitForState(ctx context.Context, states ...VMState) (VMState, error) {
+
+	if len(states) == 0 {
+		return "", fmt.Errorf("no states to wait for")
+	}
+
+	for {
+		state, err := v.Status()
+		if err != nil {
+			return "", err
+		}
+		if state.in(states) {
+			return state, nil
+		}
+		if state == VMStateBroken || state == VMStateDeleted {
+			return state, &UnexpectedStateError{
+				ID:     v.ID(),
+				State:  state,
+				Wanted: states,
+			}
+		}
+
+		select {
+		case <-time.After(statePollInterval):
+		case <-ctx.Done():
+			return state, fmt.Errorf("stopped waiting for virtual machine '%s': %v", v.ID(), ctx.Err())
+		}
+	}
+}
+
+// Restart stops the virtual machine if it is running, waits for it to become
+// ready, then starts it and waits for it to become alive.
+func (v *VirtualMachine) Restart(ctx context.Context) error {
+
+	state, err := v.Status()
+	if err != nil {
+		return err
+	}
+
+	if state.in(vmTransitions["stop"]) {
+		err = v.Stop()
+		if err != nil {
+			return err
+		}
+		_, err = v.WaitForState(ctx, VMStateReady)
+		if err != nil {
+			return err
+		}
+	} else if state != VMStateReady {
+		return &TransitionError{
+			ID:     v.ID(),
+			Action: "restart",
+			State:  state,
+		}
+	}
+
+	err = v.Start()
+	if err != nil {
+		return err
+	}
+
+	_, err = v.WaitForState(ctx, VMStateAlive)
+	return err
+}
+
+// StopAndDelete stops the virtual machine if it is running, waits for it to
+// stop, then deletes it.
+func (v *VirtualMachine) StopAndDelete(ctx context.Context) error {
+
+	state, err := v.Status()
+	if err != nil {
+		return err
+	}
+
+	if state.in(vmTransitions["stop"]) {
+		err = v.Stop()
+		if err != nil {
+			return err
+		}
+		_, err = v.WaitForState(ctx, VMStateReady, VMStateBroken)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = v.checkTransition("delete")
+	if err != nil {
+		return err
+	}
+
+	return v.Delete()
+}
@@ -1,10 +1,14 @@
 package goapi
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,11 +33,27 @@ func (m *MachinesManager) WithEnvironment(r *Repository) *MachinesManager {
 // Provision a virtual machine.
 func (m *MachinesManager) Provision(args *ProvisionArguments) (*ProvisionOperation, error) {
 
-	if args.Injections == nil {
-		args.Injections = make([]string, 0)
+	err := checkGerm(args.Germ)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.validate(args)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := args.configuration()
+	if err != nil {
+		return nil, err
 	}
+
+	injections := make([]string, 0)
 	for _, a := range args.Injections {
-		a = fmt.Sprintf(`"%s"`, a)
+		injections = append(injections, fmt.Sprintf(`"%s"`, a))
+	}
+	if config != nil {
+		injections = append(injections, fmt.Sprintf(`"%s"`, configurationInjectionID))
 	}
 
 	argsStr := make([]string, 0)
@@ -48,8 +68,8 @@ func (m *MachinesManager) Provision(args *ProvisionArguments) (*ProvisionOperati
 		argsStr = append(argsStr, fmt.Sprintf(`platform: "%s"`, args.Platform))
 	}
 	argsStr = append(argsStr, fmt.Sprintf(`start: %v`, args.PoweredOn))
-	if len(args.Injections) != 0 {
-		argsStr = append(argsStr, fmt.Sprintf("injections: [%s]", strings.Join(args.Injections, ", ")))
+	if len(injections) != 0 {
+		argsStr = append(argsStr, fmt.Sprintf("injections: [%s]", strings.Join(injections, ", ")))
 	}
 
 	req := m.environment.newRequest(fmt.Sprintf(`
@@ -68,17 +88,29 @@ func (m *MachinesManager) Provision(args *ProvisionArguments) (*ProvisionOperati
 	}
 
 	resp := new(responseContainer)
-	err := m.c.graphql.Run(m.c.ctx, req, &resp)
+	err = m.c.graphql.Run(m.c.ctx, req, &resp)
 	if err != nil {
 		return nil, err
 	}
 
-	return &ProvisionOperation{
+	op := &ProvisionOperation{
 		c:     m.c,
 		host:  m.environment.host,
 		jobID: resp.Provision.Job.ID,
 		uri:   resp.Provision.URI,
-	}, nil
+	}
+
+	if config != nil {
+		hdr := make(http.Header)
+		hdr.Set("Content-Type", "application/json")
+		err = op.Inject(configurationInjectionID, ConfigurationInjection,
+			bytes.NewReader(config), hdr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inject configuration: %v", err)
+		}
+	}
+
+	return op, nil
 }
 
 // Inject ..
@@ -105,7 +137,7 @@ func (p *ProvisionOperation) Inject(key string, itype InjectionType, value io.Re
 		}
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := p.c.http.Do(req)
 	if err != nil {
 		return err
 	}
@@ -118,21 +150,26 @@ func (p *ProvisionOperation) Inject(key string, itype InjectionType, value io.Re
 	return nil
 }
 
-// List all virtual machines.
-func (m *MachinesManager) ListMachines(cursor *Cursor) (*VirtualMachineList, error) {
+// List all virtual machines. If any field groups are provided, the fields in
+// those groups are fetched as well and made available on each item's Details.
+func (m *MachinesManager) ListMachines(cursor *Cursor, groups ...VMFieldGroup) (*VirtualMachineList, error) {
 
 	var vd, v string
 	if cursor != nil {
 		vd, v = cursor.Strings()
 	}
 
+	selection, err := vmSelection(groups...)
+	if err != nil {
+		return nil, err
+	}
+
 	req := m.environment.newRequest(fmt.Sprintf(`
 		query%s {
 			listMachines%s {
 				edges {
 					node {
-						id
-						name
+						%s
 					}
 					cursor
 				}
@@ -144,7 +181,7 @@ func (m *MachinesManager) ListMachines(cursor *Cursor) (*VirtualMachineList, err
 				}
 			}
 		}
-	`, vd, v))
+	`, vd, v, selection))
 
 	if cursor != nil {
 		cursor.AddToRequest(req)
@@ -154,7 +191,7 @@ func (m *MachinesManager) ListMachines(cursor *Cursor) (*VirtualMachineList, err
 		ListMachines objects.VMsConnection `json:"listMachines"`
 	}
 	resp := new(responseContainer)
-	err := m.c.graphql.Run(m.c.ctx, req, &resp)
+	err = m.c.graphql.Run(m.c.ctx, req, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -164,13 +201,41 @@ func (m *MachinesManager) ListMachines(cursor *Cursor) (*VirtualMachineList, err
 		Items:    make([]VirtualMachineListItem, 0),
 	}
 	for _, v := range resp.ListMachines.Edges {
-		out.Items = append(out.Items, VirtualMachineListItem{
+		item := VirtualMachineListItem{
 			Cursor: v.Cursor,
 			VirtualMachine: VirtualMachine{
+				mgr:  m,
 				id:   v.Node.ID,
 				name: v.Node.Name,
 			},
-		})
+		}
+		if len(groups) != 0 {
+			item.Details, err = newVirtualMachineDetails(&v.Node, groups)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse details of '%s': %v", v.Node.ID, err)
+			}
+		}
+		out.Items = append(out.Items, item)
+	}
+
+	return out, nil
+}
+
+// allMachines walks every page of the machine list, fetching the requested
+// field groups for each machine.
+func (m *MachinesManager) allMachines(groups ...VMFieldGroup) ([]VirtualMachineListItem, error) {
+
+	out := make([]VirtualMachineListItem, 0)
+	err := walkPages(func(curs *Cursor) (objects.PageInfo, error) {
+		list, err := m.ListMachines(curs, groups...)
+		if err != nil {
+			return objects.PageInfo{}, err
+		}
+		out = append(out, list.Items...)
+		return list.PageInfo, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return out, nil
@@ -261,4 +326,105 @@ type ProvisionArguments struct {
 	Platform     string
 	KernelType   KernelType
 	Injections   []string
+
+	// Config is a Vorteil configuration that is injected automatically
+	// once the provision operation has been created. The fields below
+	// override individual settings, and may be used with or without
+	// Config. Config itself is never modified.
+	Config *objects.VorteilConfiguration
+
+	// CPUs overrides the number of CPUs if non-zero.
+	CPUs int
+
+	// Memory overrides the amount of RAM if non-zero.
+	Memory ByteQuantity
+
+	// Env sets environment variables, replacing any of the same name.
+	Env map[string]string
+
+	// Ports exposes additional ports on the first network interface.
+	Ports []PortMapping
+}
+
+// PortMapping exposes a port of a virtual machine using a protocol.
+type PortMapping struct {
+	Protocol RouteProtocol
+	Port     int
+}
+
+// configurationInjectionID is the injection ID used for the configuration
+// generated from the typed fields of ProvisionArguments.
+const configurationInjectionID = "goapi-configuration"
+
+// configuration merges Config with the override fields, returning the
+// serialized result, or nil if there is nothing to inject.
+func (args *ProvisionArguments) configuration() ([]byte, error) {
+
+	if args.Config == nil && args.CPUs == 0 && args.Memory == 0 &&
+		len(args.Env) == 0 && len(args.Ports) == 0 {
+		return nil, nil
+	}
+
+	cfg := new(objects.VorteilConfiguration)
+	if args.Config != nil {
+		// deep copy so that the caller's configuration isn't modified
+		data, err := json.Marshal(args.Config)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(data, cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if args.CPUs != 0 {
+		cfg.VM.CPUs = args.CPUs
+	}
+	if args.Memory != 0 {
+		cfg.VM.RAM = args.Memory.String()
+	}
+
+	keys := make([]string, 0)
+	for k := range args.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var replaced bool
+		for i, t := range cfg.Env.Tuples {
+			if t.Key == k {
+				cfg.Env.Tuples[i].Value = args.Env[k]
+				replaced = true
+			}
+		}
+		if !replaced {
+			cfg.Env.Tuples = append(cfg.Env.Tuples, objects.MapTuple{
+				Key:   k,
+				Value: args.Env[k],
+			})
+		}
+	}
+
+	if len(args.Ports) != 0 && len(cfg.Networks) == 0 {
+		cfg.Networks = append(cfg.Networks, objects.NetworkInterface{})
+	}
+	for _, p := range args.Ports {
+		n := &cfg.Networks[0]
+		port := strconv.Itoa(p.Port)
+		switch p.Protocol {
+		case RouteHTTP:
+			n.HTTP = append(n.HTTP, port)
+		case RouteHTTPS:
+			n.HTTPS = append(n.HTTPS, port)
+		case RouteTCP:
+			n.TCP = append(n.TCP, port)
+		case RouteUDP:
+			n.UDP = append(n.UDP, port)
+		default:
+			return nil, fmt.Errorf("unsupported port protocol '%s'", p.Protocol)
+		}
+	}
+
+	return json.Marshal(cfg)
 }
@@ -0,0 +1,110 @@
+package goapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/sisatech/goapi/pkg/objects"
+)
+
+func TestProvisionConfiguration(t *testing.T) {
+
+	base := func() *objects.VorteilConfiguration {
+		cfg := new(objects.VorteilConfiguration)
+		cfg.VM.CPUs = 1
+		cfg.Env.Tuples = []objects.MapTuple{{Key: "A", Value: "1"}, {Key: "B", Value: "2"}}
+		cfg.Networks = []objects.NetworkInterface{{IP: "dhcp", HTTP: []string{"80"}}}
+		return cfg
+	}
+
+	tests := []struct {
+		name  string
+		args  ProvisionArguments
+		check func(cfg *objects.VorteilConfiguration) bool
+		err   bool
+	}{
+		{
+			name: "env override replaces an existing tuple",
+			args: ProvisionArguments{Config: base(), Env: map[string]string{"B": "3", "C": "4"}},
+			check: func(cfg *objects.VorteilConfiguration) bool {
+				return reflect.DeepEqual(cfg.Env.Tuples, []objects.MapTuple{
+					{Key: "A", Value: "1"}, {Key: "B", Value: "3"}, {Key: "C", Value: "4"},
+				})
+			},
+		},
+		{
+			name: "resources override the config",
+			args: ProvisionArguments{Config: base(), CPUs: 4, Memory: 512 * MiB},
+			check: func(cfg *objects.VorteilConfiguration) bool {
+				return cfg.VM.CPUs == 4 && cfg.VM.RAM == (512*MiB).String()
+			},
+		},
+		{
+			name: "ports are added to the first network",
+			args: ProvisionArguments{Config: base(), Ports: []PortMapping{{RouteHTTP, 8080}, {RouteUDP, 53}}},
+			check: func(cfg *objects.VorteilConfiguration) bool {
+				n := cfg.Networks[0]
+				return len(cfg.Networks) == 1 && n.IP == "dhcp" &&
+					reflect.DeepEqual(n.HTTP, []string{"80", "8080"}) &&
+					reflect.DeepEqual(n.UDP, []string{"53"})
+			},
+		},
+		{
+			name: "ports create a network if there is none",
+			args: ProvisionArguments{Ports: []PortMapping{{RouteTCP, 22}, {RouteHTTPS, 443}}},
+			check: func(cfg *objects.VorteilConfiguration) bool {
+				return len(cfg.Networks) == 1 &&
+					reflect.DeepEqual(cfg.Networks[0].TCP, []string{"22"}) &&
+					reflect.DeepEqual(cfg.Networks[0].HTTPS, []string{"443"})
+			},
+		},
+		{
+			name: "unknown port protocol",
+			args: ProvisionArguments{Ports: []PortMapping{{RouteProtocol("sctp"), 9}}},
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		var original []byte
+		if tt.args.Config != nil {
+			original, _ = json.Marshal(tt.args.Config)
+		}
+
+		data, err := tt.args.configuration()
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		cfg := new(objects.VorteilConfiguration)
+		if err = json.Unmarshal(data, cfg); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !tt.check(cfg) {
+			t.Errorf("%s: unexpected configuration %s", tt.name, data)
+		}
+
+		if tt.args.Config != nil {
+			after, _ := json.Marshal(tt.args.Config)
+			if string(after) != string(original) {
+				t.Errorf("%s: Config was modified", tt.name)
+			}
+		}
+	}
+
+	// nothing to inject
+	data, err := (&ProvisionArguments{Germ: "local:b/a"}).configuration()
+	if data != nil || err != nil {
+		t.Errorf("configuration without overrides = %s, %v", data, err)
+	}
+
+}
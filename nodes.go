@@ -0,0 +1,158 @@
+package goapi
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/machinebox/graphql"
+	"github.com/sisatech/goapi/pkg/objects"
+)
+
+// defaultNodeCacheTTL is how long the list of repository nodes is cached when
+// ClientConfig.NodeCacheTTL is zero.
+const defaultNodeCacheTTL = 30 * time.Second
+
+// nodeCache caches the list of repository nodes registered with the
+// environment, so that resolving repositories doesn't require a request each
+// time.
+type nodeCache struct {
+	lock    sync.Mutex
+	ttl     time.Duration
+	nodes   []objects.Node
+	fetched time.Time
+
+	// noLookup is set once the server has shown it doesn't support
+	// looking up a single node by name.
+	noLookup bool
+}
+
+func newNodeCache(ttl time.Duration) *nodeCache {
+	if ttl == 0 {
+		ttl = defaultNodeCacheTTL
+	}
+	return &nodeCache{
+		ttl: ttl,
+	}
+}
+
+// fresh returns the cached nodes, or nil if the cache is empty or expired.
+// The caller must hold the lock.
+func (n *nodeCache) fresh() []objects.Node {
+	if n.ttl < 0 || n.nodes == nil || time.Since(n.fetched) > n.ttl {
+		return nil
+	}
+	return n.nodes
+}
+
+func (n *nodeCache) invalidate() {
+	n.lock.Lock()
+	n.nodes = nil
+	n.lock.Unlock()
+}
+
+// InvalidateCache discards the cached list of repositories, causing the next
+// lookup to fetch it from the environment. The cache is invalidated
+// automatically by Connect and Disconnect.
+func (r *RepositoriesManager) InvalidateCache() {
+	r.c.nodes.invalidate()
+}
+
+// listNodes returns every repository node registered with the environment,
+// from the cache if possible.
+func (r *RepositoriesManager) listNodes() ([]objects.Node, error) {
+
+	r.c.nodes.lock.Lock()
+	defer r.c.nodes.lock.Unlock()
+
+	if nodes := r.c.nodes.fresh(); nodes != nil {
+		return nodes, nil
+	}
+
+	req := graphql.NewRequest(fmt.Sprintf(`
+		query {
+			listNodes {
+				name
+				host
+				type
+			}
+		}
+	`))
+
+	type responseContainer struct {
+		ListNodes []objects.Node `json:"listNodes"`
+	}
+	resp := new(responseContainer)
+	err := r.c.graphql.Run(r.c.ctx, req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.ListNodes == nil {
+		resp.ListNodes = make([]objects.Node, 0)
+	}
+	r.c.nodes.nodes = resp.ListNodes
+	r.c.nodes.fetched = time.Now()
+
+	return resp.ListNodes, nil
+}
+
+// lookupNode finds a single repository node by name. A fresh cache is used if
+// available; otherwise the node is requested directly, falling back to
+// listing every node if the server doesn't support direct lookups.
+func (r *RepositoriesManager) lookupNode(name string) (*objects.Node, error) {
+
+	r.c.nodes.lock.Lock()
+	nodes := r.c.nodes.fresh()
+	noLookup := r.c.nodes.noLookup
+	r.c.nodes.lock.Unlock()
+
+	if nodes == nil && !noLookup {
+		req := graphql.NewRequest(fmt.Sprintf(`
+			query {
+				node(name: "%s") {
+					name
+					host
+					type
+				}
+			}
+		`, name))
+
+		type responseContainer struct {
+			Node *objects.Node `json:"node"`
+		}
+		resp := new(responseContainer)
+		err := r.c.graphql.Run(r.c.ctx, req, &resp)
+		if err == nil {
+			if resp.Node == nil || resp.Node.Name == "" {
+				return nil, fmt.Errorf("could not find repository '%s'", name)
+			}
+			return resp.Node, nil
+		}
+		if !strings.Contains(err.Error(), "Cannot query field") {
+			return nil, err
+		}
+
+		r.c.nodes.lock.Lock()
+		r.c.nodes.noLookup = true
+		r.c.nodes.lock.Unlock()
+	}
+
+	if nodes == nil {
+		var err error
+		nodes, err = r.listNodes()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range nodes {
+		if nodes[i].Name == name {
+			n := nodes[i]
+			return &n, nil
+		}
+	}
+
+	return nil, fmt.Errorf("could not find repository '%s'", name)
+}
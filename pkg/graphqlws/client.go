@@ -2,10 +2,12 @@ package graphqlws
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,10 +17,7 @@ import (
 	"github.com/graphql-go/graphql/language/parser"
 )
 
-// TODO: keep-alive dependency
 // TODO: GQL subscription timeout
-// TODO: connect on-demand
-// TODO: automatic reconnect
 // TODO: accept-encoding gzip
 // TODO: GQL_START extensions
 
@@ -40,6 +39,26 @@ type ClientConfig struct {
 	// request to connect to the server.
 	Path string
 
+	// Secure causes the client to connect using the 'wss' scheme instead of
+	// 'ws' when the URL is built from Address and Path.
+	Secure bool
+
+	// URL can be used to provide the complete URL of the server, including
+	// its scheme, which must be either 'ws' or 'wss'. If set, Address, Path
+	// and Secure are ignored.
+	URL string
+
+	// TLSConfig is used when connecting to a server using the 'wss' scheme.
+	// It overrides the TLS configuration of the Dialer, if any. When left as
+	// nil, the Dialer's TLS configuration is used, falling back to the
+	// default configuration.
+	TLSConfig *tls.Config
+
+	// Subprotocols lists the GraphQL websocket subprotocols offered to the
+	// server, which picks the one that is used. Both 'graphql-transport-ws'
+	// and 'graphql-ws' are supported. If left empty, both are offered.
+	Subprotocols []string
+
 	// Dialer can be used to provide the 'websocket.Dialer' that will be
 	// used to connect to a server. This may be useful if the client needs
 	// to be proxied or make use of a cookiejar. When left as nil, the
@@ -64,18 +83,61 @@ type ClientConfig struct {
 	// ReadTimeout determines the maximum length of time a client will wait
 	// in between each message received from the server. This setting only
 	// comes into effect if the connected server is configured to
-	// 'keep-alive' the connection. While in effect, if this interval is
-	// ever exceeded the connection will be considered corrupt, causing the
-	// client to close itself down. If left as zero, no read timeout will
-	// be enforced.
+	// 'keep-alive' the connection, or if PingInterval is set. While in
+	// effect, if this interval is ever exceeded the connection will be
+	// considered dead, causing the client to close it. If left as zero, no
+	// read timeout will be enforced unless PingInterval is set, in which
+	// case a timeout of twice the PingInterval is used.
 	ReadTimeout time.Duration
 
+	// PingInterval determines how often the client sends a websocket ping
+	// to the server. The server's pongs keep the connection alive even if
+	// it doesn't send GraphQL keep-alive messages, and are used to measure
+	// the round trip time reported by Health. If left as zero, no pings
+	// are sent.
+	PingInterval time.Duration
+
+	// OnConnectionDead is called when a connection is closed because
+	// nothing was received from the server within the read timeout. The
+	// client then reconnects or closes down as it would for any other lost
+	// connection.
+	OnConnectionDead func(err error)
+
 	// WriteTimeout determines the maximum length of time a client will wait
 	// for the server to receive each message. If this interval is ever
 	// exceeded the connection will be considered corrupt, causing the
 	// client to close itself down. If left as zero, no write timeout will
 	// be enforced.
 	WriteTimeout time.Duration
+
+	// Reconnect enables automatic reconnection. If the connection to the
+	// server is lost the client will dial it again according to the
+	// policy, then restart every live subscription using its original
+	// query and variables. Queries and mutations whose result hadn't
+	// arrived are reported an error instead, since the server may already
+	// have run them. When left as nil, losing the connection closes the
+	// client down and reports an error to every live operation.
+	Reconnect *ReconnectPolicy
+
+	// OnStateChange is called each time the state of the client's
+	// connection changes, along with the error that caused the change, if
+	// any. It is called from the client's internal goroutines, so it
+	// should return quickly.
+	OnStateChange func(state ConnectionState, err error)
+
+	// Lazy defers dialing the server until the first operation begins, so
+	// that NewClient succeeds even if the server is unreachable. A lazy
+	// client closes its connection once it has been idle for IdleTimeout,
+	// and dials again when the next operation begins. If the connection is
+	// lost and can't be recovered, live operations are reported an error
+	// but the client remains usable.
+	Lazy bool
+
+	// IdleTimeout determines how long a lazy client keeps its connection
+	// open once no operations remain. If left as zero, a timeout of 30
+	// seconds is used. A negative value keeps the connection open until
+	// the client is closed.
+	IdleTimeout time.Duration
 }
 
 // Client manages a single connection to a GraphQL web socket server, which may
@@ -83,13 +145,42 @@ type ClientConfig struct {
 // concurrently. A Client object must be intialized using the NewClient
 // function.
 type Client struct {
+	config *ClientConfig
+	log    logger
+	url    url.URL
+
+	// connection
+	conn      *clientConn
+	health    health
+	stopping  chan bool
+	state     ConnectionState
+	connLock  sync.RWMutex
+	stateLock sync.Mutex
+	dialLock  sync.Mutex
+	idleTimer *time.Timer
+	threads   sync.WaitGroup
+
+	// operations
+	inShutdown bool
+	operations map[string]*clientOperation
+	opsLock    sync.RWMutex
+}
+
+// clientConn is a single websocket connection belonging to a Client. A Client
+// that reconnects will use a new clientConn for each connection.
+type clientConn struct {
 	config          *ClientConfig
 	log             logger
-	url             url.URL
-	conn            *websocket.Conn
+	ws              *websocket.Conn
 	inbox           chan *Message
 	readLoopClosed  chan bool
 	expectKeepAlive bool
+	health          *health
+	protocol        string
+
+	// idle is set when a lazy client closes the connection because it is
+	// no longer needed.
+	idle bool
 
 	// error propagation
 	err         error
@@ -97,17 +188,11 @@ type Client struct {
 	errLock     sync.RWMutex
 
 	threads sync.WaitGroup
-	cleanup []func()
 
 	// outbox
 	outbox       chan *Message
 	outboxClosed bool
 	outboxLock   sync.RWMutex
-
-	// operations
-	inShutdown bool
-	operations map[string]*clientOperation
-	opsLock    sync.RWMutex
 }
 
 // Subscription provides a handle for an active subscription with functions to
@@ -124,155 +209,269 @@ func (s *Subscription) WaitUntilFinished(ctx context.Context) error {
 	return s.op.WaitUntilFinished(ctx)
 }
 
-func (c *Client) dial(ctx context.Context) error {
+func (c *Client) dial(ctx context.Context) (*clientConn, error) {
 	dialer := c.config.Dialer
 	if dialer == nil {
 		dialer = websocket.DefaultDialer
 	}
-	header := c.config.Header
-	if header == nil {
-		header = make(http.Header)
+	if c.config.TLSConfig != nil {
+		d := *dialer
+		d.TLSClientConfig = c.config.TLSConfig
+		dialer = &d
 	}
-	header.Set("Sec-WebSocket-Protocol", "graphql-ws")
-	var err error
+	header := make(http.Header)
+	for k, v := range c.config.Header {
+		header[k] = v
+	}
+	protocols := c.config.Subprotocols
+	if len(protocols) == 0 {
+		protocols = defaultProtocols
+	}
+	for _, p := range protocols {
+		if !isSupportedProtocol(p) {
+			return nil, fmt.Errorf("unsupported subprotocol '%s'", p)
+		}
+	}
+	header.Set("Sec-WebSocket-Protocol", strings.Join(protocols, ", "))
 	c.log.Info("Dialing server")
-	c.conn, _, err = dialer.DialContext(ctx, c.url.String(), header)
+	ws, _, err := dialer.DialContext(ctx, c.url.String(), header)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	c.log.Info("Connected to server")
-	c.cleanup = append([]func(){func() {
-		c.log.Info("Closing the connection to the server")
-		c.reportError(c.conn.Close())
-	}}, c.cleanup...)
 
-	protocol := c.conn.Subprotocol()
-	if protocol != "graphql-ws" {
-		return errors.New("failed to negotiate 'graphql-ws' subprotocol")
+	protocol := ws.Subprotocol()
+	var negotiated bool
+	for _, p := range protocols {
+		negotiated = negotiated || p == protocol
 	}
-	return nil
+	if !negotiated {
+		_ = ws.Close()
+		return nil, fmt.Errorf("failed to negotiate a subprotocol (offered %s)", strings.Join(protocols, ", "))
+	}
+
+	return &clientConn{
+		config:         c.config,
+		log:            c.log,
+		ws:             ws,
+		inbox:          make(chan *Message),
+		outbox:         make(chan *Message),
+		readLoopClosed: make(chan bool),
+		health:         &c.health,
+		protocol:       protocol,
+	}, nil
 }
 
-func (c *Client) reportError(err error) {
-	c.errLock.Lock()
-	if !c.errReported {
-		c.errReported = true
-		c.err = err
+// connect dials the server and initializes a new connection.
+func (c *Client) connect(ctx context.Context) (*clientConn, error) {
+	cc, err := c.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial server: %v", err)
 	}
-	c.errLock.Unlock()
+
+	err = cc.initConnection(ctx)
+	if err != nil {
+		cc.discard()
+		return nil, fmt.Errorf("failed to initialize connection: %v", err)
+	}
+
+	return cc, nil
+}
+
+func (cc *clientConn) reportError(err error) {
+	cc.errLock.Lock()
+	if !cc.errReported {
+		cc.errReported = true
+		cc.err = err
+	}
+	cc.errLock.Unlock()
+}
+
+func (cc *clientConn) error() error {
+	cc.errLock.RLock()
+	defer cc.errLock.RUnlock()
+	return cc.err
 }
 
-func (c *Client) newWriteDeadline() time.Time {
+func (cc *clientConn) newWriteDeadline() time.Time {
 	var t time.Time
-	if c.config.WriteTimeout != 0 {
-		t = time.Now().Add(c.config.WriteTimeout)
+	if cc.config.WriteTimeout != 0 {
+		t = time.Now().Add(cc.config.WriteTimeout)
 	}
 	return t
 }
 
-func (c *Client) writeLoop() {
-	c.log.Info("Starting write loop")
+func (cc *clientConn) writeLoop() {
+	cc.log.Info("Starting write loop")
 	for {
-		msg, more := <-c.outbox
+		msg, more := <-cc.outbox
 		if !more {
-			c.log.Info("Write loop terminating because the outbox has been closed")
+			cc.log.Info("Write loop terminating because the outbox has been closed")
 			break
 		}
 
-		err := c.conn.SetWriteDeadline(c.newWriteDeadline())
+		if cc.protocol == ProtocolGraphQLTransportWS {
+			msg = toTransportWS(msg)
+			if msg == nil {
+				continue
+			}
+		}
+
+		err := cc.ws.SetWriteDeadline(cc.newWriteDeadline())
 		if err != nil {
 			err = fmt.Errorf("failed to set a write deadline: %v", err)
-			c.reportError(err)
+			cc.reportError(err)
 			break
 		}
 
-		err = c.conn.WriteJSON(msg)
+		err = cc.ws.WriteJSON(msg)
 		if err != nil {
 			err = fmt.Errorf("failed to write to the connection: %v", err)
-			c.reportError(err)
+			cc.reportError(err)
 			break
 		}
 	}
-	c.log.Info("Write loop finished")
-	c.threads.Done()
-}
 
-func (c *Client) startWriteLoop() {
-	c.threads.Add(1)
-	c.cleanup = append([]func(){func() {
-		c.outboxLock.Lock()
-		if !c.outboxClosed {
-			close(c.outbox)
-			c.outboxClosed = true
+	// unblock the read loop in case the write loop failed, and drain the
+	// outbox so that nothing blocks sending to it
+	_ = cc.ws.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), cc.newWriteDeadline())
+	_ = cc.ws.Close()
+	go func() {
+		for range cc.outbox {
 		}
-		c.outboxLock.Unlock()
-	}}, c.cleanup...)
-	go c.writeLoop()
+	}()
+	cc.log.Info("Write loop finished")
+	cc.threads.Done()
 }
 
-func (c *Client) newReadDeadline() time.Time {
+func (cc *clientConn) newReadDeadline() time.Time {
 	var t time.Time
-	if c.expectKeepAlive {
-		if c.config.ReadTimeout != 0 {
-			t = time.Now().Add(c.config.ReadTimeout)
-		}
+	if timeout := cc.readTimeout(); timeout != 0 {
+		t = time.Now().Add(timeout)
 	}
 	return t
 }
 
-func (c *Client) readLoop() {
-	c.log.Info("Starting read loop")
+func (cc *clientConn) readLoop() {
+	cc.log.Info("Starting read loop")
 	for {
-		err := c.conn.SetReadDeadline(c.newReadDeadline())
+		err := cc.ws.SetReadDeadline(cc.newReadDeadline())
 		if err != nil {
 			err = fmt.Errorf("failed to set a read deadline: %v", err)
-			c.reportError(err)
+			cc.reportError(err)
 			break
 		}
 
 		msg := new(Message)
-		err = c.conn.ReadJSON(&msg)
+		err = cc.ws.ReadJSON(&msg)
 		if err != nil {
+			dead := isTimeout(err)
 			err = fmt.Errorf("failed to read from the connection: %v", err)
-			c.reportError(err)
+			cc.reportError(err)
+			if dead {
+				cc.log.Error("Connection is dead: %v", err)
+				if cc.config.OnConnectionDead != nil {
+					cc.config.OnConnectionDead(err)
+				}
+			}
 			break
 		}
+		cc.health.received()
 
-		c.log.Info("Read loop queuing a new message for the dispatcher")
-		c.inbox <- msg
+		if msg.Type == MessageTypeGQLConnectionKeepAlive && !cc.expectKeepAlive {
+			cc.expectKeepAlive = true
+			cc.health.lock.Lock()
+			cc.health.keepAlive = true
+			cc.health.lock.Unlock()
+		}
+
+		msgs := []*Message{msg}
+		if cc.protocol == ProtocolGraphQLTransportWS {
+			switch msg.Type {
+			case MessageTypeGQLPing:
+				cc.log.Info("Responding to ping from server")
+				cc.send(&Message{Type: MessageTypeGQLPong, Payload: msg.Payload})
+				continue
+			case MessageTypeGQLPong:
+				continue
+			}
+			msgs = fromTransportWS(msg, false)
+		}
+
+		cc.log.Info("Read loop queuing a new message for the dispatcher")
+		for _, m := range msgs {
+			cc.inbox <- m
+		}
 	}
 
-	close(c.inbox)
-	c.log.Info("Read loop finished")
-	c.threads.Done()
+	close(cc.inbox)
+	cc.log.Info("Read loop finished")
 
 	// force outbox to close in case the closed connection was not intentional
-	close(c.readLoopClosed)
-	c.outboxLock.Lock()
-	if !c.outboxClosed {
-		close(c.outbox)
-		c.outboxClosed = true
+	close(cc.readLoopClosed)
+	cc.closeOutbox()
+	cc.threads.Done()
+}
+
+func (cc *clientConn) closeOutbox() {
+	cc.outboxLock.Lock()
+	if !cc.outboxClosed {
+		close(cc.outbox)
+		cc.outboxClosed = true
 	}
-	c.outboxLock.Unlock()
+	cc.outboxLock.Unlock()
 }
 
-func (c *Client) startReadLoop() {
-	c.threads.Add(1)
-	c.cleanup = append([]func(){func() {
-		// reporting a nil error causes all subsequent errors to be disregarded
-		c.reportError(nil)
-		err := c.conn.Close()
-		if err != nil {
-			c.reportError(err)
+func (cc *clientConn) send(message *Message) {
+	if cc == nil {
+		return
+	}
+	cc.outboxLock.RLock()
+	if !cc.outboxClosed {
+		cc.outbox <- message
+	}
+	cc.outboxLock.RUnlock()
+}
+
+// close immediately closes the connection. Reporting a nil error first causes
+// all subsequent errors to be disregarded.
+func (cc *clientConn) close() {
+	cc.log.Info("Closing the connection to the server")
+	cc.reportError(nil)
+	err := cc.ws.Close()
+	if err != nil {
+		cc.log.Error("Failed to close the connection: %v", err)
+	}
+}
+
+// discard closes a connection that will never be dispatched, draining its
+// inbox so that the read loop can finish.
+func (cc *clientConn) discard() {
+	cc.close()
+	go func() {
+		for range cc.inbox {
 		}
-	}}, c.cleanup...)
-	go c.readLoop()
+	}()
 }
 
-func (c *Client) dispatcher() {
+// terminate sends a GraphQL connection terminate message and closes the
+// outbox, leaving the write loop to close the connection once it has been
+// flushed.
+func (cc *clientConn) terminate() {
+	cc.log.Info("Queuing GraphQL connection terminate message")
+	cc.send(&Message{
+		Type:    MessageTypeGQLConnectionTerminate,
+		Payload: map[string]interface{}{},
+	})
+	cc.reportError(nil)
+	cc.closeOutbox()
+}
+
+func (c *Client) dispatcher(cc *clientConn) {
 	c.log.Info("Starting dispatcher loop")
 	for {
-		msg, more := <-c.inbox
+		msg, more := <-cc.inbox
 		if !more {
 			break
 		}
@@ -282,10 +481,10 @@ func (c *Client) dispatcher() {
 		switch msg.Type {
 		case MessageTypeGQLConnectionKeepAlive:
 			c.log.Info("Received keep-alive message from server")
-			c.expectKeepAlive = true
 		case MessageTypeGQLData:
 			c.opsLock.RLock()
 			o, ok := c.operations[msg.ID]
+			c.opsLock.RUnlock()
 			if ok {
 				data, err := msg.GQLDataPayload()
 				if err != nil {
@@ -296,81 +495,72 @@ func (c *Client) dispatcher() {
 			} else {
 				c.log.Error("Discarding data payload for unknown operation: %s", msg.ID)
 			}
-			c.opsLock.RUnlock()
 		case MessageTypeGQLError:
 			c.opsLock.RLock()
 			o, ok := c.operations[msg.ID]
+			c.opsLock.RUnlock()
 			if ok {
 				o.error(msg.Payload)
 			} else {
 				c.log.Error("Discarding error for an unknown operation: %s: %v", msg.ID, msg.Payload)
 			}
-			c.opsLock.RUnlock()
 		case MessageTypeGQLComplete:
-			c.opsLock.Lock()
+			c.opsLock.RLock()
 			o, ok := c.operations[msg.ID]
-			if ok {
-				delete(c.operations, msg.ID)
+			c.opsLock.RUnlock()
+			if ok && c.remove(o) {
 				o.complete()
 				o.log.Info("Cleaned up completed operation")
 			} else {
 				c.log.Error("Server indicated an unknown operation was completed: %s", msg.ID)
 			}
-			c.opsLock.Unlock()
 		case MessageTypeGQLConnectionError:
 			c.log.Error("Server ignored a message due to parsing errors: %v", msg.Payload)
+		case MessageTypeGQLConnectionTerminate:
+			c.log.Info("Server is terminating the connection")
 		default:
 			c.log.Error("Server sent unexpected message type: %s", msg.Type)
 		}
 	}
 
 	c.log.Info("Dispatcher loop finished")
-	c.threads.Done()
-}
-
-func (c *Client) startDispatcher() {
-	c.threads.Add(1)
-	c.cleanup = append([]func(){func() {}}, c.cleanup...)
-	go c.dispatcher()
 }
 
 func (c *Client) send(message *Message) {
-	c.outboxLock.RLock()
-	if !c.outboxClosed {
-		c.outbox <- message
-	}
-	c.outboxLock.RUnlock()
+	c.connLock.RLock()
+	cc := c.conn
+	c.connLock.RUnlock()
+	cc.send(message)
 }
 
-func (c *Client) initConnection(ctx context.Context) error {
-	c.startWriteLoop()
-	c.startReadLoop()
-	c.log.Info("Initializing websocket with graphql-ws subprotocol")
+func (cc *clientConn) initConnection(ctx context.Context) error {
+	cc.health.reset()
+	cc.ws.SetPongHandler(cc.handlePong)
+	cc.threads.Add(2)
+	go cc.writeLoop()
+	go cc.readLoop()
+	if cc.config.PingInterval != 0 {
+		cc.threads.Add(1)
+		go cc.pingLoop()
+	}
+	cc.log.Info("Initializing websocket with %s subprotocol", cc.protocol)
 	// send GraphQL connection init message
-	if c.config.InitialPayload == nil {
-		c.config.InitialPayload = make(map[string]interface{})
+	payload := cc.config.InitialPayload
+	if payload == nil {
+		payload = make(map[string]interface{})
 	}
-	c.log.Info("Queuing GraphQL connection init message")
-	c.send(&Message{
+	cc.log.Info("Queuing GraphQL connection init message")
+	cc.send(&Message{
 		Type:    MessageTypeGQLConnectionInit,
-		Payload: c.config.InitialPayload,
+		Payload: payload,
 	})
-	c.cleanup = append([]func(){
-		func() {
-			c.log.Info("Queuing GraphQL connection terminate message")
-			c.send(&Message{
-				Type:    MessageTypeGQLConnectionTerminate,
-				Payload: map[string]interface{}{},
-			})
-		},
-	}, c.cleanup...)
 
 	// wait for server's acknowledgement of the connection
 	var m *Message
 	select {
-	case msg, more := <-c.inbox:
+	case msg, more := <-cc.inbox:
 		if !more {
-			return fmt.Errorf("connection closed: %v", c.err)
+			return fmt.Errorf("connection closed: %v", cc.error())
 		}
 		m = msg
 	case <-ctx.Done():
@@ -385,31 +575,26 @@ func (c *Client) initConnection(ctx context.Context) error {
 		return fmt.Errorf("server responded to GraphQL connection init message with unexpected message type: %s", m.Type)
 	}
 
-	c.startDispatcher()
+	return nil
+}
 
-	// defer a cleanup of each clientOperation
-	c.cleanup = append([]func(){
-		func() {
-			var liveOperations []*clientOperation
-			c.opsLock.RLock()
-			for _, v := range c.operations {
-				liveOperations = append(liveOperations, v)
-			}
-			c.opsLock.RUnlock()
+func (c *Client) parseURL() error {
+	if c.config.URL == "" {
+		c.url = url.URL{Scheme: "ws", Host: c.config.Address, Path: c.config.Path}
+		if c.config.Secure {
+			c.url.Scheme = "wss"
+		}
+		return nil
+	}
 
-			var wg sync.WaitGroup
-			wg.Add(len(liveOperations))
-			for _, o := range liveOperations {
-				go func(op *clientOperation) {
-					op.Stop()
-					_ = op.WaitUntilFinished(context.Background())
-					wg.Done()
-				}(o)
-			}
-			wg.Wait()
-			c.log.Info("Stopped all live operations")
-		},
-	}, c.cleanup...)
+	u, err := url.Parse(c.config.URL)
+	if err != nil {
+		return fmt.Errorf("invalid server url: %v", err)
+	}
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return fmt.Errorf("invalid server url scheme '%s': must be 'ws' or 'wss'", u.Scheme)
+	}
+	c.url = *u
 	return nil
 }
 
@@ -418,38 +603,89 @@ func (c *Client) initConnection(ctx context.Context) error {
 func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 	c := new(Client)
 	c.config = config
-	c.url = url.URL{Scheme: "ws", Host: config.Address, Path: config.Path}
+	err := c.parseURL()
+	if err != nil {
+		return nil, err
+	}
 	// initialize logger
 	c.log.logger = c.config.Logger
 
-	c.readLoopClosed = make(chan bool)
-	c.inbox = make(chan *Message)
-	c.outbox = make(chan *Message)
+	c.stopping = make(chan bool)
 	c.operations = make(map[string]*clientOperation)
 
-	// dial
-	err := c.dial(ctx)
-	if err != nil {
-		_ = c.Close()
-		return nil, fmt.Errorf("failed to dial server: %v", err)
+	if c.config.Lazy {
+		c.setState(StateIdle, nil)
+		return c, nil
 	}
 
-	// initialize connection
-	err = c.initConnection(ctx)
+	c.setState(StateConnecting, nil)
+	cc, err := c.connect(ctx)
 	if err != nil {
-		_ = c.Close()
-		return nil, fmt.Errorf("failed to initialize connection: %v", err)
+		c.setState(StateClosed, err)
+		return nil, err
 	}
+	c.conn = cc
+	c.setState(StateConnected, nil)
+
+	c.threads.Add(1)
+	go c.run(cc)
+
 	return c, nil
 }
 
+// stopOperations stops every live operation and waits for them to finish.
+func (c *Client) stopOperations(ctx context.Context) {
+	var liveOperations []*clientOperation
+	c.opsLock.RLock()
+	for _, v := range c.operations {
+		liveOperations = append(liveOperations, v)
+	}
+	c.opsLock.RUnlock()
+
+	var wg sync.WaitGroup
+	wg.Add(len(liveOperations))
+	for _, o := range liveOperations {
+		go func(op *clientOperation) {
+			op.Stop()
+			_ = op.WaitUntilFinished(ctx)
+			wg.Done()
+		}(o)
+	}
+	wg.Wait()
+	c.log.Info("Stopped all live operations")
+}
+
+// stop prevents the client from reconnecting and closes the current
+// connection, terminating it gracefully if requested.
+func (c *Client) stop(graceful bool) {
+	c.connLock.Lock()
+	select {
+	case <-c.stopping:
+	default:
+		close(c.stopping)
+	}
+	cc := c.conn
+	c.connLock.Unlock()
+
+	if cc == nil {
+		// an idle client has no connection to supervise its shutdown
+		c.finish(nil)
+		return
+	}
+
+	if graceful {
+		cc.terminate()
+	} else {
+		cc.close()
+	}
+}
+
 // Shutdown attempts to close the client gracefully, terminating all ongoing
 // operations correctly before closing the websocket and terminating the
 // connection. The provided context can be used to cancel the shutdown
 // prematurely in the event that it stalls or takes longer than is acceptable.
 func (c *Client) Shutdown(ctx context.Context) error {
 	ch := make(chan bool)
-	var cancelled bool
 	c.opsLock.Lock()
 	if c.inShutdown {
 		c.opsLock.Unlock()
@@ -461,12 +697,10 @@ func (c *Client) Shutdown(ctx context.Context) error {
 	c.log.Info("Shutting down client")
 
 	go func() {
-		for _, fn := range c.cleanup {
-			if cancelled {
-				break
-			}
-			fn()
+		if c.State() == StateConnected {
+			c.stopOperations(ctx)
 		}
+		c.stop(true)
 		c.threads.Wait()
 		close(ch)
 	}()
@@ -476,7 +710,7 @@ func (c *Client) Shutdown(ctx context.Context) error {
 		c.log.Info("Client has shut down")
 		return nil
 	case <-ctx.Done():
-		cancelled = true
+		c.stop(false)
 		return fmt.Errorf("failed to shutdown the client: %v", ctx.Err())
 	}
 }
@@ -485,17 +719,12 @@ func (c *Client) Shutdown(ctx context.Context) error {
 // and cleans up resources in use by the Client.
 func (c *Client) Close() error {
 	c.log.Info("Closing client")
+	c.opsLock.Lock()
 	c.inShutdown = true
-	var wg sync.WaitGroup
-	wg.Add(len(c.cleanup))
-	for _, fn := range c.cleanup {
-		go func(fn func()) {
-			fn()
-			wg.Done()
-		}(fn)
-	}
+	c.opsLock.Unlock()
+	c.stop(false)
 	go func() {
-		wg.Wait()
+		c.threads.Wait()
 		c.log.Info("Client closed")
 	}()
 	return nil
@@ -553,17 +782,48 @@ type clientOperation struct {
 	id       string
 	cfg      operationConfig
 	finished chan bool
+
+	// operation is the type of GraphQL operation: "query", "mutation" or
+	// "subscription".
+	operation string
+
+	// startedOn is the connection the operation was most recently started
+	// on, which is used to avoid starting it twice on the same connection.
+	startedOn *clientConn
+	stopped   bool
+	startLock sync.Mutex
 }
 
+// complete closes the operation's finished channel. The caller must have
+// already removed the operation from the client's operations.
 func (o *clientOperation) complete() {
-	delete(o.operations, o.id)
 	close(o.finished)
 }
 
 func (o *clientOperation) error(x interface{}) {
 	o.log.Info("Delivering GraphQL error message payload via ErrorCallback")
-	s := fmt.Sprintf("%v", x)
-	err := errors.New(s)
+	var err error
+	switch v := x.(type) {
+	case error:
+		err = v
+	case []interface{}:
+		// graphql-transport-ws servers send a list of GraphQL errors
+		errs := make(GQLErrors, 0, len(v))
+		for _, e := range v {
+			m, ok := e.(map[string]interface{})
+			if !ok {
+				break
+			}
+			msg, _ := m["message"].(string)
+			errs = append(errs, GQLError{Message: msg})
+		}
+		if len(errs) == len(v) {
+			err = errs
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("%v", x)
+	}
 	o.cfg.ErrorCallback(err)
 }
 
@@ -576,32 +836,38 @@ func (o *clientOperation) Stop() {
 	select {
 	case <-o.finished:
 	default:
+		o.startLock.Lock()
+		o.stopped = true
+		cc := o.startedOn
+		o.startLock.Unlock()
 		o.log.Info("Queuing GraphQL stop message")
 		o.send(&Message{
 			ID:   o.id,
 			Type: MessageTypeGQLStop,
 		})
+		// graphql-transport-ws servers don't acknowledge a stopped
+		// operation
+		if cc != nil && cc.protocol == ProtocolGraphQLTransportWS && o.remove(o) {
+			o.complete()
+			o.log.Info("Cleaned up stopped operation")
+		}
 	}
 }
 
 func (o *clientOperation) WaitUntilFinished(ctx context.Context) error {
 	select {
 	case <-o.finished:
-	case <-o.readLoopClosed:
-		o.opsLock.Lock()
-		o.complete()
-		o.opsLock.Unlock()
-		o.log.Info("Cleaned up operation because of a closed connection")
 	case <-ctx.Done():
 		return fmt.Errorf("context cancelled or timed out before client finished: %v", ctx.Err())
 	}
 	return nil
 }
 
-func (c *Client) beginOperation(config operationConfig) (*clientOperation, error) {
+func (c *Client) beginOperation(operation string, config operationConfig) (*clientOperation, error) {
 	o := new(clientOperation)
 	o.Client = c
 	o.cfg = config
+	o.operation = operation
 	o.finished = make(chan bool)
 
 	c.opsLock.Lock()
@@ -625,6 +891,7 @@ func (c *Client) beginOperation(config operationConfig) (*clientOperation, error
 	o.log.logger = o.Client.log
 	o.log.suffix = fmt.Sprintf(" (operation: %s)", o.id)
 	c.operations[o.id] = o
+	c.cancelIdle()
 	c.opsLock.Unlock()
 
 	o.log.Info("Added a new operation to the client")
@@ -634,9 +901,56 @@ func (c *Client) beginOperation(config operationConfig) (*clientOperation, error
 	return o, nil
 }
 
+// remove removes an operation from the client, returning false if it had
+// already been removed.
+func (c *Client) remove(o *clientOperation) bool {
+	c.opsLock.Lock()
+	defer c.opsLock.Unlock()
+	_, ok := c.operations[o.id]
+	if !ok {
+		return false
+	}
+	delete(c.operations, o.id)
+	if len(c.operations) == 0 {
+		c.scheduleIdle()
+	}
+	return true
+}
+
 func (o *clientOperation) initialize() {
+	ctx, cancel := o.stoppingContext()
+	cc, err := o.acquire(ctx)
+	cancel()
+	if err != nil {
+		if o.remove(o) {
+			o.error(err)
+			o.complete()
+		}
+		return
+	}
+
+	if !o.start(cc) && o.remove(o) {
+		o.complete()
+		o.log.Info("Cleaned up operation stopped before it started")
+	}
+}
+
+// start sends the operation's start message on the connection, unless it has
+// already been started there. It returns false if the operation has been
+// stopped.
+func (o *clientOperation) start(cc *clientConn) bool {
+	o.startLock.Lock()
+	defer o.startLock.Unlock()
+	if o.stopped {
+		return false
+	}
+	if o.startedOn == cc {
+		return true
+	}
+	o.startedOn = cc
+
 	o.log.Info("Queuing GraphQL start message")
-	o.send(&Message{
+	cc.send(&Message{
 		ID:   o.id,
 		Type: MessageTypeGQLStart,
 		Payload: GQLStartPayload{
@@ -645,11 +959,13 @@ func (o *clientOperation) initialize() {
 			OperationName: o.cfg.OperationName,
 		},
 	})
+	return true
 }
 
 type onceConfig interface {
 	validate() error
 	queryConfig() queryConfig
+	operationType() string
 }
 
 func (c *Client) once(ctx context.Context, config onceConfig) (*GQLDataPayload, error) {
@@ -662,7 +978,7 @@ func (c *Client) once(ctx context.Context, config onceConfig) (*GQLDataPayload,
 	var gqlError error
 	cfg := config.queryConfig()
 
-	o, err := c.beginOperation(operationConfig{
+	o, err := c.beginOperation(config.operationType(), operationConfig{
 		Query:         cfg.Query,
 		Variables:     cfg.Variables,
 		OperationName: cfg.OperationName,
@@ -704,6 +1020,10 @@ func (c QueryConfig) queryConfig() queryConfig {
 	return queryConfig(c)
 }
 
+func (c *QueryConfig) operationType() string {
+	return "query"
+}
+
 // Query performs a GraphQL query over the client's websocket.
 func (c *Client) Query(ctx context.Context, config *QueryConfig) (*GQLDataPayload, error) {
 	return c.once(ctx, config)
@@ -725,6 +1045,10 @@ func (c *MutationConfig) queryConfig() queryConfig {
 	return queryConfig(*c)
 }
 
+func (c *MutationConfig) operationType() string {
+	return "mutation"
+}
+
 // Mutation performs a GraphQL mutation over the client's websocket.
 func (c *Client) Mutation(ctx context.Context, config *MutationConfig) (*GQLDataPayload, error) {
 	return c.once(ctx, config)
@@ -756,7 +1080,7 @@ func (c *Client) Subscription(config *SubscriptionConfig) (*Subscription, error)
 		return nil, err
 	}
 
-	o, err := c.beginOperation(operationConfig(*config))
+	o, err := c.beginOperation("subscription", operationConfig(*config))
 	if err != nil {
 		return nil, err
 	}
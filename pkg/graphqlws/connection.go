@@ -1,12 +1,13 @@
 package graphqlws
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"sync"
 	"time"
 
-	"github.com/gofrs/uuid"
 	"github.com/gorilla/websocket"
 )
 
@@ -35,47 +36,61 @@ func (m *operationManager) get(uid string) (*operation, bool) {
 	return v, ok
 }
 
-func (m *operationManager) add(o *operation) {
-	for {
-		uid, err := uuid.NewV4()
-		if err != nil {
-			panic(err)
-		}
-		o.uid = uid.String()
-		m.lock.Lock()
-		if m.closed {
-			return // TODO: return error
-		}
-		_, exists := m.ops[o.uid]
-		if exists {
-			m.lock.Unlock()
-			continue
-		}
-		m.ops[o.uid] = o
-		m.lock.Unlock()
-		break
+// add registers an operation under the ID chosen by the client, returning an
+// error if the ID is already in use or the operation exceeds a limit.
+func (m *operationManager) add(o *operation) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.closed {
+		return errConnectionClosed
+	}
+	if _, exists := m.ops[o.id]; exists {
+		return errOperationExists
+	}
+	if limit := m.cfg.MaxOperationsPerConnection; limit > 0 && len(m.ops) >= limit {
+		return errTooManyOperations
 	}
+	if !m.reserveOperation() {
+		return errTooManyOperations
+	}
+	m.ops[o.id] = o
+	return nil
 }
 
 func (m *operationManager) remove(o *operation) {
 	m.lock.Lock()
-	delete(m.ops, o.uid)
+	_, ok := m.ops[o.id]
+	if ok {
+		delete(m.ops, o.id)
+		m.releaseOperation()
+	}
 	m.lock.Unlock()
+	o.cancel()
 }
 
-func (m *operationManager) shutdown() {
+// shutdown stops every operation and prevents any more from being added. If
+// complete is true the client is told that each operation has completed.
+func (m *operationManager) shutdown(complete bool) {
 	m.lock.Lock()
-	var wg sync.WaitGroup
-	wg.Add(len(m.ops))
+	if m.closed {
+		m.lock.Unlock()
+		return
+	}
 	m.closed = true
+	ops := make([]*operation, 0, len(m.ops))
 	for _, o := range m.ops {
-		go func(op *operation) {
-			// TODO: op.close()
-			wg.Done()
-		}(o)
+		ops = append(ops, o)
+		m.releaseOperation()
 	}
+	m.ops = make(map[string]*operation)
 	m.lock.Unlock()
-	wg.Wait()
+
+	for _, o := range ops {
+		o.cancel()
+		if complete {
+			o.finish()
+		}
+	}
 }
 
 func (m *operationManager) Range(fn func(o *operation)) {
@@ -90,6 +105,10 @@ type connection struct {
 	*Server
 	id            string
 	ws            *websocket.Conn
+	request       *http.Request
+	ctx           context.Context
+	cancel        context.CancelFunc
+	protocol      string
 	wg            sync.WaitGroup
 	inbox         chan *Message
 	outbox        chan *Message
@@ -98,15 +117,22 @@ type connection struct {
 	connError     error
 	connErrorLock sync.Mutex
 
+	// closeCode and closeReason are sent in the close frame when the
+	// connection is closed, if closeCode is non-zero.
+	closeCode   int
+	closeReason string
+
 	// operations
 	operations *operationManager
 }
 
-func (s *Server) newConnection(ws *websocket.Conn) *connection {
+func (s *Server) newConnection(ws *websocket.Conn, r *http.Request) *connection {
 	c := new(connection)
 	c.Server = s
 	c.ws = ws
-	c.connections.add(c)
+	c.request = r
+	c.ctx, c.cancel = context.WithCancel(r.Context())
+	c.protocol = ws.Subprotocol()
 	c.inbox = make(chan *Message)
 	c.outbox = make(chan *Message)
 	c.keepAlive = make(chan time.Time, 1)
@@ -129,48 +155,166 @@ func (c *connection) closeWithError(err error) {
 		c.connError = err
 	}
 	c.connErrorLock.Unlock()
-	c.operations.shutdown()
+	c.operations.shutdown(false)
 	close(c.outbox)
-	c.wg.Wait()
 }
 
 func (c *connection) close() {
 	c.closeWithError(nil)
 }
 
+// setCloseCode determines the code and reason sent in the close frame, unless
+// one has already been chosen.
+func (c *connection) setCloseCode(code int, reason string) {
+	c.connErrorLock.Lock()
+	if c.closeCode == 0 {
+		c.closeCode = code
+		c.closeReason = closeReason(reason)
+	}
+	c.connErrorLock.Unlock()
+}
+
+// shutdown closes the connection on behalf of the server once every operation
+// has been completed.
+func (c *connection) shutdown() {
+	c.operations.shutdown(true)
+	if c.protocol != ProtocolGraphQLTransportWS {
+		_ = c.send(&Message{
+			Type: MessageTypeGQLConnectionTerminate,
+		})
+	}
+	c.setCloseCode(websocket.CloseGoingAway, "server shutting down")
+	c.closeWithError(ErrServerClosed)
+}
+
+// refuse turns away a connection the server has no room for, before any of
+// its routines have started.
+func (c *connection) refuse(err error) {
+	c.log.Error(fmt.Sprintf("Refusing connection: %v", err))
+	if c.protocol != ProtocolGraphQLTransportWS {
+		_ = c.ws.WriteJSON(&Message{
+			Type:    MessageTypeGQLConnectionError,
+			Payload: err.Error(),
+		})
+	}
+	msg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, closeReason(err.Error()))
+	_ = c.ws.WriteMessage(websocket.CloseMessage, msg)
+}
+
+// kill closes the connection immediately, abandoning any messages that have
+// not yet been written.
+func (c *connection) kill() {
+	c.setCloseCode(websocket.CloseGoingAway, "server shutting down")
+	c.closeWithError(ErrServerClosed)
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	_ = c.ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	_ = c.ws.Close()
+}
+
+// defaultInitTimeout is how long the server waits for a client to initialize
+// its connection when ServerConfig.InitTimeout is zero.
+const defaultInitTimeout = 10 * time.Second
+
+func (c *connection) initTimeout() <-chan time.Time {
+	timeout := c.cfg.InitTimeout
+	if timeout == 0 {
+		timeout = defaultInitTimeout
+	}
+	if timeout < 0 {
+		return nil
+	}
+	return time.After(timeout)
+}
+
+// reject closes a connection that misbehaved. Legacy clients are sent a
+// connection_error message, whereas graphql-transport-ws clients are sent the
+// close code.
+func (c *connection) reject(code int, err error) {
+	c.log.Error(err.Error())
+	if c.protocol == ProtocolGraphQLTransportWS {
+		c.setCloseCode(code, err.Error())
+	} else {
+		_ = c.send(&Message{
+			Type:    MessageTypeGQLConnectionError,
+			Payload: err.Error(),
+		})
+	}
+	c.closeWithError(err)
+}
+
 func (c *connection) run() {
 	c.wg.Add(1)
 	go c.readLoop()
 
-	msg, more := <-c.inbox
-	if more {
+	c.wg.Add(1)
+	go c.writeLoop()
+
+	select {
+	case msg, more := <-c.inbox:
+		if !more {
+			break
+		}
 		if msg.Type != MessageTypeGQLConnectionInit {
-			err := errors.New("client failed to send connection_init")
-			c.log.Error(err.Error())
-			c.closeWithError(err)
-		} else {
-			c.log.Info("Received message from client: connection_init")
+			code := closeCodeBadRequest
+			if msg.Type == MessageTypeGQLStart {
+				code = closeCodeUnauthorized
+			}
+			go c.discardInbox()
+			c.reject(code, errors.New("client failed to send connection_init"))
+			break
 		}
-	}
+		c.log.Info("Received message from client: connection_init")
 
-	c.wg.Add(1)
-	go c.writeLoop()
+		err := c.authenticate(msg.Payload)
+		if err != nil {
+			go c.discardInbox()
+			c.reject(closeCodeForbidden, err)
+			break
+		}
 
-	_ = c.send(&Message{
-		Type: MessageTypeGQLConnectionAck,
-	})
+		_ = c.send(&Message{
+			Type: MessageTypeGQLConnectionAck,
+		})
 
-	c.wg.Add(1)
-	go c.inboxLoop()
+		c.wg.Add(1)
+		go c.inboxLoop()
 
-	c.wg.Add(1)
-	go c.keepAliveLoop()
+		c.wg.Add(1)
+		go c.keepAliveLoop()
+	case <-c.initTimeout():
+		go c.discardInbox()
+		c.reject(closeCodeInitTimeout, errors.New("connection initialisation timeout"))
+	}
 
 	c.wg.Wait()
+	c.cancel()
 	c.connections.remove(c)
 	c.log.Info("Connection terminated")
 }
 
+// authenticate passes the connection_init payload to the OnConnect hook, if
+// any, and keeps the context it returns for the connection's operations.
+func (c *connection) authenticate(payload interface{}) error {
+	if c.cfg.OnConnect == nil {
+		return nil
+	}
+	ctx, err := c.cfg.OnConnect(c.ctx, c.request, payload)
+	if err != nil {
+		return fmt.Errorf("connection rejected: %v", err)
+	}
+	if ctx != nil {
+		c.ctx = ctx
+	}
+	return nil
+}
+
+// discardInbox drains messages sent to a connection that was rejected, so that
+// the read loop can finish.
+func (c *connection) discardInbox() {
+	for range c.inbox {
+	}
+}
+
 func (c *connection) keepAliveLoop() {
 	defer func() {
 		c.wg.Done()
@@ -209,7 +353,22 @@ func (c *connection) readLoop() {
 			c.closeWithError(err)
 			return
 		}
-		c.inbox <- msg
+
+		msgs := []*Message{msg}
+		if c.protocol == ProtocolGraphQLTransportWS {
+			switch msg.Type {
+			case MessageTypeGQLPing:
+				_ = c.send(&Message{Type: MessageTypeGQLPong, Payload: msg.Payload})
+				continue
+			case MessageTypeGQLPong:
+				continue
+			}
+			msgs = fromTransportWS(msg, true)
+		}
+
+		for _, m := range msgs {
+			c.inbox <- m
+		}
 	}
 }
 
@@ -239,7 +398,13 @@ func (c *connection) writeLoop() {
 	defer func() {
 		c.log.Info("Write loop terminated")
 		close(c.keepAlive)
-		_ = c.ws.WriteMessage(websocket.CloseMessage, []byte{})
+		c.connErrorLock.Lock()
+		code, reason := c.closeCode, c.closeReason
+		c.connErrorLock.Unlock()
+		if code == 0 {
+			code = websocket.CloseNormalClosure
+		}
+		_ = c.ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
 		c.ws.Close()
 		c.wg.Done()
 	}()
@@ -248,6 +413,12 @@ func (c *connection) writeLoop() {
 		if !more {
 			return
 		}
+		if c.protocol == ProtocolGraphQLTransportWS {
+			message = toTransportWS(message)
+			if message == nil {
+				continue
+			}
+		}
 		if c.cfg.WriteTimeout != 0 {
 			err := c.ws.SetWriteDeadline(c.writeDeadline())
 			if err != nil {
@@ -263,7 +434,10 @@ func (c *connection) writeLoop() {
 			return
 		}
 
-		c.keepAlive <- time.Now()
+		select {
+		case c.keepAlive <- time.Now():
+		default:
+		}
 		c.log.Info(fmt.Sprintf("Sent a message to the server: %s ", message.Type))
 	}
 }
@@ -286,8 +460,14 @@ func (c *connection) inboxLoop() {
 			c.stop(message)
 		case MessageTypeGQLConnectionTerminate:
 			go c.terminate()
+		case MessageTypeGQLConnectionInit:
+			go c.reject(closeCodeTooManyInitRequests, errors.New("too many initialisation requests"))
 		default:
 			err := fmt.Errorf("Received unsupported message type: %v", message.Type)
+			if c.protocol == ProtocolGraphQLTransportWS {
+				go c.reject(closeCodeBadRequest, err)
+				continue
+			}
 			c.log.Error(err.Error())
 			_ = c.send(&Message{
 				Type:    MessageTypeGQLConnectionError,
@@ -0,0 +1,196 @@
+package graphqlws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+)
+
+type testUserKey struct{}
+
+// authSchema returns a schema whose 'me' field resolves to the user stored in
+// the resolver's context by OnConnect.
+func authSchema(t *testing.T) graphql.Schema {
+
+	me := &graphql.Field{
+		Type: graphql.String,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			user, _ := p.Context.Value(testUserKey{}).(string)
+			return user, nil
+		},
+	}
+
+	secret := &graphql.Field{
+		Type:    graphql.String,
+		Args:    graphql.FieldConfigArgument{"x": &graphql.ArgumentConfig{Type: graphql.String}},
+		Resolve: me.Resolve,
+	}
+
+	s, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name:   "Query",
+			Fields: graphql.Fields{"me": me, "secret": secret},
+		}),
+		Subscription: graphql.NewObject(graphql.ObjectConfig{
+			Name:   "Subscription",
+			Fields: graphql.Fields{"me": me},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+// authenticate accepts connections whose init payload carries the token
+// 'good', storing the X-User request header as the user.
+func authenticate(ctx context.Context, r *http.Request, payload interface{}) (context.Context, error) {
+	m, _ := payload.(map[string]interface{})
+	if m["token"] != "good" {
+		return nil, errors.New("bad token")
+	}
+	return context.WithValue(ctx, testUserKey{}, r.Header.Get("X-User")), nil
+}
+
+func authClient(protocol, addr, token string) (*Client, error) {
+	return NewClient(context.Background(), &ClientConfig{
+		Address:        addr,
+		Subprotocols:   []string{protocol},
+		Header:         http.Header{"X-User": {"alice"}},
+		InitialPayload: map[string]string{"token": token},
+	})
+}
+
+func TestOnConnect(t *testing.T) {
+
+	_, addr, stop := startTestServer(t, &ServerConfig{
+		Schema:    authSchema(t),
+		OnConnect: authenticate,
+	})
+	defer stop()
+
+	for _, protocol := range testProtocols {
+		_, err := authClient(protocol, addr, "bad")
+		if err == nil || !strings.Contains(err.Error(), "bad token") {
+			t.Errorf("%s: bad token returned %v, want the OnConnect error", protocol, err)
+		}
+
+		c, err := authClient(protocol, addr, "good")
+		if err != nil {
+			t.Fatalf("%s: %v", protocol, err)
+		}
+		defer c.Close()
+
+		// the context returned by OnConnect reaches the resolvers
+		res, err := c.Query(context.Background(), &QueryConfig{Query: "query { me }"})
+		if err != nil {
+			t.Fatalf("%s: %v", protocol, err)
+		}
+		data, _ := res.Data.(map[string]interface{})
+		if data["me"] != "alice" {
+			t.Errorf("%s: query resolved %v, want user 'alice'", protocol, res.Data)
+		}
+
+		// including those run by subscriptions
+		events, err := c.SubscribeInto(context.Background(), "subscription { me }", nil,
+			func() interface{} { return new(map[string]string) }, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case e := <-events:
+			if e.Err != nil || (*e.Value.(*map[string]string))["me"] != "alice" {
+				t.Errorf("%s: subscription resolved %+v, want user 'alice'", protocol, e)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: subscription received nothing", protocol)
+		}
+	}
+
+}
+
+func TestOnConnectRejectionCloseCode(t *testing.T) {
+
+	_, addr, stop := startTestServer(t, &ServerConfig{OnConnect: authenticate})
+	defer stop()
+
+	d := websocket.Dialer{Subprotocols: []string{ProtocolGraphQLTransportWS}}
+	ws, _, err := d.Dial("ws://"+addr+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	err = ws.WriteJSON(&Message{Type: MessageTypeGQLConnectionInit, Payload: map[string]string{"token": "bad"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = ws.ReadMessage()
+	if !websocket.IsCloseError(err, closeCodeForbidden) {
+		t.Errorf("got %v, want close code %d", err, closeCodeForbidden)
+	}
+
+}
+
+func TestOnOperation(t *testing.T) {
+
+	var lock sync.Mutex
+	var seen []*OperationParams
+	_, addr, stop := startTestServer(t, &ServerConfig{
+		Schema:    authSchema(t),
+		OnConnect: authenticate,
+		OnOperation: func(ctx context.Context, op *OperationParams) error {
+			lock.Lock()
+			seen = append(seen, op)
+			lock.Unlock()
+			if op.OperationName == "Secret" {
+				return errors.New("forbidden for " + ctx.Value(testUserKey{}).(string))
+			}
+			return nil
+		},
+	})
+	defer stop()
+
+	for _, protocol := range testProtocols {
+		c, err := authClient(protocol, addr, "good")
+		if err != nil {
+			t.Fatalf("%s: %v", protocol, err)
+		}
+		defer c.Close()
+
+		_, err = c.Query(context.Background(), &QueryConfig{Query: "query Me { me }", OperationName: "Me"})
+		if err != nil {
+			t.Errorf("%s: allowed operation was rejected: %v", protocol, err)
+		}
+
+		res, err := c.Query(context.Background(), &QueryConfig{
+			Query:         "query Secret($x: String) { secret(x: $x) }",
+			OperationName: "Secret",
+			Variables:     map[string]interface{}{"x": "1"},
+		})
+		if err == nil || !strings.Contains(err.Error(), "operation rejected: forbidden for alice") {
+			t.Errorf("%s: rejected operation returned %v, %v", protocol, res, err)
+		}
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(seen) != 2*len(testProtocols) {
+		t.Fatalf("OnOperation was called %d times, want %d", len(seen), 2*len(testProtocols))
+	}
+	op := seen[1]
+	if op.ID == "" || op.Document == nil || op.Query != "query Secret($x: String) { secret(x: $x) }" ||
+		op.Variables["x"] != "1" {
+		t.Errorf("OnOperation received incomplete parameters: %+v", op)
+	}
+
+}
@@ -0,0 +1,114 @@
+package graphqlws
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ConnectionHealth describes the liveness of a Client's connection.
+type ConnectionHealth struct {
+	State ConnectionState
+
+	// LastMessage is when the client last received anything from the
+	// server, including keep-alive messages and pongs.
+	LastMessage time.Time
+
+	// RTT is the round trip time of the most recent ping, or zero if no
+	// ping has been answered.
+	RTT time.Duration
+
+	// KeepAlive is true if the server is sending GraphQL keep-alive
+	// messages on the current connection.
+	KeepAlive bool
+}
+
+// health is the liveness information shared by each of a Client's
+// connections.
+type health struct {
+	lock        sync.Mutex
+	lastMessage time.Time
+	rtt         time.Duration
+	keepAlive   bool
+}
+
+func (h *health) received() {
+	h.lock.Lock()
+	h.lastMessage = time.Now()
+	h.lock.Unlock()
+}
+
+// reset clears the information that only applies to a single connection.
+func (h *health) reset() {
+	h.lock.Lock()
+	h.rtt = 0
+	h.keepAlive = false
+	h.lock.Unlock()
+}
+
+// Health reports the state and liveness of the client's connection.
+func (c *Client) Health() ConnectionHealth {
+	c.health.lock.Lock()
+	defer c.health.lock.Unlock()
+	return ConnectionHealth{
+		State:       c.State(),
+		LastMessage: c.health.lastMessage,
+		RTT:         c.health.rtt,
+		KeepAlive:   c.health.keepAlive,
+	}
+}
+
+// readTimeout returns the longest the connection may go without receiving
+// anything before it is considered dead, or zero if there is no limit.
+func (cc *clientConn) readTimeout() time.Duration {
+	if cc.config.ReadTimeout != 0 {
+		if cc.expectKeepAlive || cc.config.PingInterval != 0 {
+			return cc.config.ReadTimeout
+		}
+		return 0
+	}
+	return 2 * cc.config.PingInterval
+}
+
+// handlePong records the round trip time of a ping, whose payload is the time
+// it was sent, and extends the read deadline.
+func (cc *clientConn) handlePong(appData string) error {
+	cc.health.received()
+	sent, err := strconv.ParseInt(appData, 10, 64)
+	if err == nil {
+		cc.health.lock.Lock()
+		cc.health.rtt = time.Since(time.Unix(0, sent))
+		cc.health.lock.Unlock()
+	}
+	return cc.ws.SetReadDeadline(cc.newReadDeadline())
+}
+
+// pingLoop sends a websocket ping every PingInterval until the connection is
+// closed.
+func (cc *clientConn) pingLoop() {
+	defer cc.threads.Done()
+	cc.log.Info("Starting ping loop")
+	ticker := time.NewTicker(cc.config.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			payload := strconv.FormatInt(time.Now().UnixNano(), 10)
+			err := cc.ws.WriteControl(websocket.PingMessage, []byte(payload), cc.newWriteDeadline())
+			if err != nil {
+				cc.log.Error("Failed to send ping: %v", err)
+			}
+		case <-cc.readLoopClosed:
+			cc.log.Info("Ping loop finished")
+			return
+		}
+	}
+}
+
+func isTimeout(err error) bool {
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
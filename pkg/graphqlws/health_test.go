@@ -0,0 +1,124 @@
+package graphqlws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestReadTimeout(t *testing.T) {
+
+	tests := []struct {
+		readTimeout     time.Duration
+		pingInterval    time.Duration
+		expectKeepAlive bool
+		want            time.Duration
+	}{
+		{want: 0},
+		{readTimeout: time.Second, want: 0},
+		{readTimeout: time.Second, expectKeepAlive: true, want: time.Second},
+		{readTimeout: time.Second, pingInterval: time.Minute, want: time.Second},
+		{pingInterval: time.Second, want: 2 * time.Second},
+		{pingInterval: time.Second, expectKeepAlive: true, want: 2 * time.Second},
+		{expectKeepAlive: true, want: 0},
+	}
+
+	for _, tt := range tests {
+		cc := &clientConn{
+			config: &ClientConfig{
+				ReadTimeout:  tt.readTimeout,
+				PingInterval: tt.pingInterval,
+			},
+			expectKeepAlive: tt.expectKeepAlive,
+		}
+		if got := cc.readTimeout(); got != tt.want {
+			t.Errorf("%+v: readTimeout() = %v, want %v", tt, got, tt.want)
+		}
+	}
+
+}
+
+func TestHealth(t *testing.T) {
+
+	_, addr, stop := startTestServer(t, &ServerConfig{})
+	defer stop()
+
+	before := time.Now()
+	c, err := NewClient(context.Background(), &ClientConfig{
+		Address:      addr,
+		PingInterval: 20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	waitFor(t, func() bool { return c.Health().RTT > 0 })
+
+	h := c.Health()
+	if h.State != StateConnected {
+		t.Errorf("state = %v, want %v", h.State, StateConnected)
+	}
+	if h.LastMessage.Before(before) {
+		t.Errorf("last message at %v, before the client started", h.LastMessage)
+	}
+
+}
+
+func TestOnConnectionDead(t *testing.T) {
+
+	// a server that accepts the connection and then stops reading, so that
+	// pings go unanswered
+	done := make(chan struct{})
+	defer close(done)
+	upgrader := websocket.Upgrader{Subprotocols: []string{ProtocolGraphQLWS}}
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		msg := new(Message)
+		if err = ws.ReadJSON(msg); err != nil || msg.Type != MessageTypeGQLConnectionInit {
+			return
+		}
+		if err = ws.WriteJSON(&Message{Type: MessageTypeGQLConnectionAck}); err != nil {
+			return
+		}
+		<-done
+	}))
+	defer hs.Close()
+
+	dead := make(chan error, 1)
+	c, err := NewClient(context.Background(), &ClientConfig{
+		Address:      strings.TrimPrefix(hs.URL, "http://"),
+		Subprotocols: []string{ProtocolGraphQLWS},
+		PingInterval: 20 * time.Millisecond,
+		OnConnectionDead: func(err error) {
+			select {
+			case dead <- err:
+			default:
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	select {
+	case err = <-dead:
+		if err == nil {
+			t.Error("OnConnectionDead called without an error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnConnectionDead not called")
+	}
+	waitFor(t, func() bool { return c.State() != StateConnected })
+
+}
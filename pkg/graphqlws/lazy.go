@@ -0,0 +1,120 @@
+package graphqlws
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// defaultIdleTimeout is how long a lazy client keeps an unused connection open
+// when ClientConfig.IdleTimeout is zero.
+const defaultIdleTimeout = 30 * time.Second
+
+// acquire returns the client's connection, dialing the server first if the
+// client is idle.
+func (c *Client) acquire(ctx context.Context) (*clientConn, error) {
+	c.dialLock.Lock()
+	defer c.dialLock.Unlock()
+
+	c.connLock.RLock()
+	cc := c.conn
+	c.connLock.RUnlock()
+	if cc != nil {
+		return cc, nil
+	}
+
+	c.setState(StateConnecting, nil)
+	cc, err := c.connect(ctx)
+	if err != nil {
+		c.setState(StateIdle, err)
+		return nil, err
+	}
+
+	c.connLock.Lock()
+	select {
+	case <-c.stopping:
+		c.connLock.Unlock()
+		cc.discard()
+		return nil, errors.New("client has been closed")
+	default:
+	}
+	c.conn = cc
+	c.connLock.Unlock()
+	c.setState(StateConnected, nil)
+
+	c.threads.Add(1)
+	go c.run(cc)
+
+	return cc, nil
+}
+
+// scheduleIdle starts the idle timer of a lazy client. The caller must hold
+// the opsLock.
+func (c *Client) scheduleIdle() {
+	if !c.config.Lazy || c.config.IdleTimeout < 0 {
+		return
+	}
+	timeout := c.config.IdleTimeout
+	if timeout == 0 {
+		timeout = defaultIdleTimeout
+	}
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+	}
+	c.idleTimer = time.AfterFunc(timeout, c.closeIfIdle)
+}
+
+// cancelIdle stops the idle timer, if any. The caller must hold the opsLock.
+func (c *Client) cancelIdle() {
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+		c.idleTimer = nil
+	}
+}
+
+// closeIfIdle gracefully closes the connection of a lazy client that has no
+// live operations.
+func (c *Client) closeIfIdle() {
+	c.dialLock.Lock()
+	defer c.dialLock.Unlock()
+
+	c.opsLock.Lock()
+	if len(c.operations) > 0 || c.inShutdown || c.State() != StateConnected {
+		c.opsLock.Unlock()
+		return
+	}
+	c.connLock.Lock()
+	cc := c.conn
+	c.conn = nil
+	c.connLock.Unlock()
+	c.opsLock.Unlock()
+
+	if cc == nil {
+		return
+	}
+
+	c.log.Info("Closing idle connection")
+	cc.idle = true
+	cc.terminate()
+	c.setState(StateIdle, nil)
+}
+
+// disconnectIfIdle returns a lazy client whose connection has been lost to
+// idle if it has no live operations, rather than reconnecting.
+func (c *Client) disconnectIfIdle(err error) bool {
+	c.dialLock.Lock()
+	defer c.dialLock.Unlock()
+
+	c.opsLock.Lock()
+	if len(c.operations) > 0 {
+		c.opsLock.Unlock()
+		return false
+	}
+	c.connLock.Lock()
+	c.conn = nil
+	c.connLock.Unlock()
+	c.opsLock.Unlock()
+
+	c.setState(StateIdle, err)
+	return true
+}
@@ -0,0 +1,152 @@
+package graphqlws
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLazyClientUnreachable(t *testing.T) {
+
+	// reserve an address and free it, so that nothing is listening on it
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	c, err := NewClient(context.Background(), &ClientConfig{Address: addr, Lazy: true})
+	if err != nil {
+		t.Fatalf("lazy client failed to start without a server: %v", err)
+	}
+	defer c.Close()
+	if c.State() != StateIdle {
+		t.Errorf("state = %v, want %v", c.State(), StateIdle)
+	}
+
+	// operations fail, but the client stays usable
+	for i := 0; i < 2; i++ {
+		_, err = c.Query(context.Background(), &QueryConfig{Query: "query { count }"})
+		if err == nil {
+			t.Fatal("query succeeded without a server")
+		}
+		if c.State() != StateIdle {
+			t.Errorf("state after failed query = %v, want %v", c.State(), StateIdle)
+		}
+	}
+
+}
+
+func TestLazyClientIdle(t *testing.T) {
+
+	srv, err := NewServer(&ServerConfig{Schema: testSchema(t), PollingInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dials int32
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&dials, 1)
+		srv.ServeHTTP(w, r)
+	}))
+	defer hs.Close()
+	defer srv.Close()
+	addr := strings.TrimPrefix(hs.URL, "http://")
+
+	c, err := NewClient(context.Background(), &ClientConfig{
+		Address:     addr,
+		Lazy:        true,
+		IdleTimeout: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if n := atomic.LoadInt32(&dials); n != 0 {
+		t.Fatalf("lazy client dialled %d times before any operation", n)
+	}
+
+	// the first operation dials, and the connection is closed once idle
+	_, err = c.Query(context.Background(), &QueryConfig{Query: "query { count }"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&dials); n != 1 {
+		t.Errorf("dialled %d times for the first query, want 1", n)
+	}
+	waitFor(t, func() bool { return c.State() == StateIdle })
+
+	// a live subscription keeps the connection open past the idle timeout
+	sub, err := subscribe(t, c, "subscription { count }")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&dials); n != 2 {
+		t.Errorf("dialled %d times after going idle, want 2", n)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if c.State() != StateConnected {
+		t.Errorf("state with a live subscription = %v, want %v", c.State(), StateConnected)
+	}
+
+	sub.Stop()
+	waitFor(t, func() bool { return c.State() == StateIdle })
+
+}
+
+func TestLazyClientLostConnection(t *testing.T) {
+
+	srv, addr, stop := startTestServer(t, &ServerConfig{PollingInterval: time.Hour})
+	defer stop()
+
+	c, err := NewClient(context.Background(), &ClientConfig{Address: addr, Lazy: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	data := make(chan bool, 1)
+	errs := make(chan error, 1)
+	sub, err := c.Subscription(&SubscriptionConfig{
+		Query: "subscription { count }",
+		DataCallback: func(p *GQLDataPayload) {
+			data <- true
+		},
+		ErrorCallback: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-data:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription received no data")
+	}
+
+	// without a reconnect policy live operations fail, and the client
+	// returns to idle rather than closing down
+	_ = srv.Close()
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription received no error after the connection was lost")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = sub.WaitUntilFinished(ctx); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return c.State() == StateIdle })
+
+}
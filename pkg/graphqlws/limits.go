@@ -0,0 +1,141 @@
+package graphqlws
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+var (
+	errTooManyConnections = errors.New("too many connections")
+	errTooManyOperations  = errors.New("too many operations")
+	errOperationExists    = errors.New("operation already exists")
+	errConnectionClosed   = errors.New("connection closed")
+)
+
+// maxQueryCost caps the depth and complexity calculated for a query, so that
+// queries spreading fragments many times over can't overflow them.
+const maxQueryCost = math.MaxInt32
+
+// reserveOperation counts an operation against the server's MaxOperations
+// limit, returning false if there is no room for it.
+func (s *Server) reserveOperation() bool {
+	if s.cfg.MaxOperations <= 0 {
+		atomic.AddInt32(&s.operations, 1)
+		return true
+	}
+	for {
+		n := atomic.LoadInt32(&s.operations)
+		if int(n) >= s.cfg.MaxOperations {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&s.operations, n, n+1) {
+			return true
+		}
+	}
+}
+
+func (s *Server) releaseOperation() {
+	atomic.AddInt32(&s.operations, -1)
+}
+
+// checkQueryLimits returns an error if a query is nested more deeply or
+// selects more fields than the server allows.
+func (s *Server) checkQueryLimits(document *ast.Document) error {
+	if s.cfg.MaxQueryDepth <= 0 && s.cfg.MaxQueryComplexity <= 0 {
+		return nil
+	}
+	depth, complexity := measureQuery(document)
+	if s.cfg.MaxQueryDepth > 0 && depth > s.cfg.MaxQueryDepth {
+		return fmt.Errorf("query depth of %d exceeds the limit of %d", depth, s.cfg.MaxQueryDepth)
+	}
+	if s.cfg.MaxQueryComplexity > 0 && complexity > s.cfg.MaxQueryComplexity {
+		return fmt.Errorf("query complexity of %d exceeds the limit of %d", complexity, s.cfg.MaxQueryComplexity)
+	}
+	return nil
+}
+
+type queryCost struct {
+	depth      int
+	complexity int
+}
+
+// measureQuery returns the deepest level of nested fields in a document's
+// operations, and the number of fields they select. Fields selected through a
+// fragment are counted every time the fragment is spread.
+func measureQuery(document *ast.Document) (depth, complexity int) {
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, definition := range document.Definitions {
+		if fd, ok := definition.(*ast.FragmentDefinition); ok && fd.Name != nil {
+			fragments[fd.Name.Value] = fd
+		}
+	}
+
+	// fragments are measured once and remembered, so that spreading them
+	// repeatedly doesn't make measuring the query expensive
+	measured := make(map[string]queryCost)
+	visiting := make(map[string]bool)
+
+	var measure func(ss *ast.SelectionSet) queryCost
+	measure = func(ss *ast.SelectionSet) queryCost {
+		var cost queryCost
+		if ss == nil {
+			return cost
+		}
+		add := func(c queryCost) {
+			if c.depth > cost.depth {
+				cost.depth = c.depth
+			}
+			cost.complexity += c.complexity
+			if cost.complexity > maxQueryCost {
+				cost.complexity = maxQueryCost
+			}
+		}
+		for _, selection := range ss.Selections {
+			switch s := selection.(type) {
+			case *ast.Field:
+				c := measure(s.SelectionSet)
+				c.depth++
+				c.complexity++
+				add(c)
+			case *ast.InlineFragment:
+				add(measure(s.SelectionSet))
+			case *ast.FragmentSpread:
+				if s.Name == nil {
+					continue
+				}
+				name := s.Name.Value
+				c, ok := measured[name]
+				if !ok {
+					fd, exists := fragments[name]
+					if !exists || visiting[name] {
+						continue
+					}
+					visiting[name] = true
+					c = measure(fd.SelectionSet)
+					delete(visiting, name)
+					measured[name] = c
+				}
+				add(c)
+			}
+		}
+		return cost
+	}
+
+	for _, definition := range document.Definitions {
+		if od, ok := definition.(*ast.OperationDefinition); ok {
+			c := measure(od.SelectionSet)
+			if c.depth > depth {
+				depth = c.depth
+			}
+			complexity += c.complexity
+			if complexity > maxQueryCost {
+				complexity = maxQueryCost
+			}
+		}
+	}
+	return depth, complexity
+}
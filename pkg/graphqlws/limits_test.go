@@ -0,0 +1,299 @@
+package graphqlws
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// nestedSchema returns a schema with fields nested three levels deep, under
+// both the query and subscription types: 'a { n b { n c { n } } }'.
+func nestedSchema(t *testing.T) graphql.Schema {
+
+	one := func(p graphql.ResolveParams) (interface{}, error) {
+		return 1, nil
+	}
+	object := func(name string, fields graphql.Fields) *graphql.Object {
+		fields["n"] = &graphql.Field{Type: graphql.Int, Resolve: one}
+		return graphql.NewObject(graphql.ObjectConfig{Name: name, Fields: fields})
+	}
+
+	c := object("C", graphql.Fields{})
+	b := object("B", graphql.Fields{"c": &graphql.Field{Type: c, Resolve: one}})
+	a := object("A", graphql.Fields{"b": &graphql.Field{Type: b, Resolve: one}})
+	root := func() graphql.Fields {
+		return graphql.Fields{"a": &graphql.Field{Type: a, Resolve: one}}
+	}
+
+	s, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query:        graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: root()}),
+		Subscription: graphql.NewObject(graphql.ObjectConfig{Name: "Subscription", Fields: root()}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestMeasureQuery(t *testing.T) {
+
+	tests := []struct {
+		query      string
+		depth      int
+		complexity int
+	}{
+		{"{ a { n } }", 2, 2},
+		{"{ a { n b { n c { n } } } }", 4, 6},
+		{"{ a { ... on A { b { n } } } }", 3, 3},
+		{"{ a { ...F ...F } } fragment F on A { n b { n } }", 3, 7},
+		{"query X { a { n } } query Y { a { b { c { n } } } }", 4, 6},
+		{"{ a { ...Missing } }", 1, 1},
+		{"{ a { ...F } } fragment F on A { ...F n }", 2, 2},
+	}
+
+	for _, tt := range tests {
+		doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		depth, complexity := measureQuery(doc)
+		if depth != tt.depth || complexity != tt.complexity {
+			t.Errorf("measureQuery('%s') = %d, %d, want %d, %d",
+				tt.query, depth, complexity, tt.depth, tt.complexity)
+		}
+	}
+
+}
+
+func TestMeasureQueryFragmentBlowup(t *testing.T) {
+
+	// each fragment spreads the previous one twice, so expanding the query
+	// would select an astronomical number of fields
+	var b strings.Builder
+	b.WriteString("{ a { ...F40 } } fragment F0 on A { n }")
+	for i := 1; i <= 40; i++ {
+		b.WriteString(" fragment F" + itoa(i) + " on A { ...F" + itoa(i-1) + " ...F" + itoa(i-1) + " }")
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: b.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, complexity := measureQuery(doc)
+	if complexity != maxQueryCost {
+		t.Errorf("complexity = %d, want it capped at %d", complexity, maxQueryCost)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("measuring the query took %v", d)
+	}
+
+}
+
+func itoa(i int) string {
+	if i < 10 {
+		return string(rune('0' + i))
+	}
+	return itoa(i/10) + string(rune('0'+i%10))
+}
+
+func TestQueryLimitRejections(t *testing.T) {
+
+	_, addr, stop := startTestServer(t, &ServerConfig{
+		Schema:             nestedSchema(t),
+		MaxQueryDepth:      3,
+		MaxQueryComplexity: 4,
+	})
+	defer stop()
+
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{query: "query { a { n b { n } } }"},
+		{query: "query { a { b { c { n } } } }", err: "query depth of 4 exceeds the limit of 3"},
+		{query: "query { a { n b { n } } x: a { n } }", err: "query complexity of 6 exceeds the limit of 4"},
+	}
+
+	for _, protocol := range testProtocols {
+		c := newTestClient(t, addr, protocol)
+		defer c.Close()
+
+		for _, tt := range tests {
+			_, err := c.Query(context.Background(), &QueryConfig{Query: tt.query})
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("%s: '%s' was rejected: %v", protocol, tt.query, err)
+				}
+				continue
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: '%s' returned %v, want '%s'", protocol, tt.query, err, tt.err)
+			}
+		}
+	}
+
+}
+
+func TestOperationLimitRejections(t *testing.T) {
+
+	for _, protocol := range testProtocols {
+		srv, addr, stop := startTestServer(t, &ServerConfig{
+			MaxOperationsPerConnection: 2,
+			MaxOperations:              3,
+			PollingInterval:            time.Hour,
+		})
+
+		c1 := newTestClient(t, addr, protocol)
+		c2 := newTestClient(t, addr, protocol)
+
+		var subs []*Subscription
+		for i, tt := range []struct {
+			c      *Client
+			reject bool
+		}{
+			{c: c1},
+			{c: c1},
+			{c: c1, reject: true}, // per-connection limit
+			{c: c2},
+			{c: c2, reject: true}, // server-wide limit
+		} {
+			sub, err := subscribe(t, tt.c, "subscription { count }")
+			if tt.reject {
+				if err == nil || !strings.Contains(err.Error(), errTooManyOperations.Error()) {
+					t.Errorf("%s: subscription %d returned %v, want '%v'", protocol, i, err, errTooManyOperations)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s: subscription %d: %v", protocol, i, err)
+			}
+			subs = append(subs, sub)
+		}
+
+		// queries never count against the limits
+		_, err := c2.Query(context.Background(), &QueryConfig{Query: "query { count }"})
+		if err != nil {
+			t.Errorf("%s: query rejected at the operation limit: %v", protocol, err)
+		}
+
+		// stopping a subscription makes room for another
+		subs[0].Stop()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = subs[0].WaitUntilFinished(ctx)
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, func() bool { return atomic.LoadInt32(&srv.operations) == 2 })
+		if _, err = subscribe(t, c2, "subscription { count }"); err != nil {
+			t.Errorf("%s: subscription rejected after another stopped: %v", protocol, err)
+		}
+
+		// closing connections releases their operations
+		c1.Close()
+		c2.Close()
+		waitFor(t, func() bool { return atomic.LoadInt32(&srv.operations) == 0 })
+		stop()
+	}
+
+}
+
+func TestMaxConnections(t *testing.T) {
+
+	_, addr, stop := startTestServer(t, &ServerConfig{MaxConnections: 1})
+	defer stop()
+
+	c := newTestClient(t, addr, ProtocolGraphQLTransportWS)
+
+	// refused with a plain HTTP response, without upgrading
+	d := websocket.Dialer{Subprotocols: []string{ProtocolGraphQLTransportWS}}
+	ws, resp, err := d.Dial("ws://"+addr+"/", nil)
+	if err == nil {
+		ws.Close()
+		t.Fatal("connection over the limit was upgraded")
+	}
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("connection over the limit returned %v, want status %d", resp, http.StatusServiceUnavailable)
+	}
+
+	c.Close()
+	waitFor(t, func() bool {
+		ws, _, err := d.Dial("ws://"+addr+"/", nil)
+		if err != nil {
+			return false
+		}
+		ws.Close()
+		return true
+	})
+
+}
+
+func TestMaxMessageSize(t *testing.T) {
+
+	_, addr, stop := startTestServer(t, &ServerConfig{MaxMessageSize: 256})
+	defer stop()
+
+	for _, protocol := range testProtocols {
+		c := newTestClient(t, addr, protocol)
+		defer c.Close()
+
+		_, err := c.Query(context.Background(), &QueryConfig{Query: "query { count }"})
+		if err != nil {
+			t.Fatalf("%s: %v", protocol, err)
+		}
+
+		query := "query { count " + strings.Repeat(" ", 512) + "}"
+		_, err = c.Query(context.Background(), &QueryConfig{Query: query})
+		if err == nil {
+			t.Errorf("%s: oversized message was accepted", protocol)
+		}
+	}
+
+}
+
+func TestInitTimeout(t *testing.T) {
+
+	_, addr, stop := startTestServer(t, &ServerConfig{InitTimeout: 50 * time.Millisecond})
+	defer stop()
+
+	dial := func(protocol string) *websocket.Conn {
+		d := websocket.Dialer{Subprotocols: []string{protocol}}
+		ws, _, err := d.Dial("ws://"+addr+"/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+		return ws
+	}
+
+	// graphql-transport-ws clients are sent the close code
+	ws := dial(ProtocolGraphQLTransportWS)
+	defer ws.Close()
+	_, _, err := ws.ReadMessage()
+	if !websocket.IsCloseError(err, closeCodeInitTimeout) {
+		t.Errorf("graphql-transport-ws: got %v, want close code %d", err, closeCodeInitTimeout)
+	}
+
+	// graphql-ws clients are sent a connection_error message
+	ws = dial(ProtocolGraphQLWS)
+	defer ws.Close()
+	msg := new(Message)
+	err = ws.ReadJSON(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != MessageTypeGQLConnectionError {
+		t.Errorf("graphql-ws: got a '%s' message, want '%s'", msg.Type, MessageTypeGQLConnectionError)
+	}
+
+}
@@ -23,6 +23,14 @@ const (
 	MessageTypeGQLConnectionKeepAlive MessageType = "ka"
 )
 
+// GQL message types only used by the graphql-transport-ws subprotocol
+const (
+	MessageTypeGQLSubscribe MessageType = "subscribe"
+	MessageTypeGQLNext      MessageType = "next"
+	MessageTypeGQLPing      MessageType = "ping"
+	MessageTypeGQLPong      MessageType = "pong"
+)
+
 // Message is the generalized form for a GraphQL over websocket message.
 type Message struct {
 	Payload interface{} `json:"payload,omitempty"`
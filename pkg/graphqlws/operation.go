@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/graphql-go/graphql"
@@ -14,9 +15,20 @@ import (
 	"github.com/graphql-go/graphql/language/parser"
 )
 
+// OperationParams describes an operation a client has asked to start.
+type OperationParams struct {
+	ID            string
+	Query         string
+	OperationName string
+	Variables     map[string]interface{}
+
+	// Document is the parsed query, which has already been validated
+	// against the schema.
+	Document *ast.Document
+}
+
 type operation struct {
 	*connection
-	uid           string
 	id            string
 	query         string
 	operationName string
@@ -27,6 +39,8 @@ type operation struct {
 	lastUpdate time.Time
 	updated    chan time.Time
 	update     Update
+	done       chan struct{}
+	doneOnce   sync.Once
 }
 
 func (c *connection) start(m *Message) {
@@ -38,15 +52,28 @@ func (c *connection) start(m *Message) {
 			ID:      m.ID,
 			Payload: err.Error(),
 		})
-		_ = c.send(&Message{
-			Type: MessageTypeGQLComplete,
-			ID:   m.ID,
-		})
+		// an error finishes a graphql-transport-ws operation by itself
+		if c.protocol != ProtocolGraphQLTransportWS {
+			_ = c.send(&Message{
+				Type: MessageTypeGQLComplete,
+				ID:   m.ID,
+			})
+		}
+	}
+
+	if _, exists := c.operations.get(m.ID); exists {
+		err := fmt.Errorf("subscriber for %s already exists", m.ID)
+		c.log.Error(err.Error())
+		if c.protocol == ProtocolGraphQLTransportWS {
+			go c.reject(closeCodeSubscriberExists, err)
+		}
+		return
 	}
 
 	o := new(operation)
 	o.connection = c
-	o.updated = make(chan time.Time)
+	o.updated = make(chan time.Time, 1)
+	o.done = make(chan struct{})
 	o.id = m.ID
 
 	// process arguments
@@ -60,6 +87,10 @@ func (c *connection) start(m *Message) {
 		return
 	}
 	for k, v := range p {
+		if v == nil {
+			// clients may send null in place of omitted fields
+			continue
+		}
 		switch k {
 		case "operationName":
 			o.operationName, ok = v.(string)
@@ -110,8 +141,25 @@ func (c *connection) start(m *Message) {
 		return
 	}
 
-	// trigger first response
-	o.execute()
+	err = c.checkQueryLimits(document)
+	if err != nil {
+		gqlError(err)
+		return
+	}
+
+	if c.cfg.OnOperation != nil {
+		err = c.cfg.OnOperation(c.ctx, &OperationParams{
+			ID:            o.id,
+			Query:         o.query,
+			OperationName: o.operationName,
+			Variables:     o.variables,
+			Document:      document,
+		})
+		if err != nil {
+			gqlError(fmt.Errorf("operation rejected: %v", err))
+			return
+		}
+	}
 
 	// initialize operation fields
 	var fields []string
@@ -153,19 +201,34 @@ func (c *connection) start(m *Message) {
 
 	// terminate operation if no subscriptions are involved
 	if !requiresSubscription {
+		o.execute()
 		o.finish()
-	} else {
-		// add operation to connection
-		c.operations.add(o)
-		o.subscribe()
+		return
 	}
 
+	// add operation to connection before triggering the first response, so
+	// that operations over the limits never run
+	err = c.operations.add(o)
+	if err != nil {
+		gqlError(err)
+		return
+	}
+	o.execute()
+	o.subscribe()
+
 }
 
 func (c *connection) stop(m *Message) {
 	c.log.Info("Client operation terminated")
 	o, ok := c.operations.get(m.ID)
-	if ok {
+	if !ok {
+		return
+	}
+	// graphql-transport-ws clients don't expect a stopped operation to be
+	// acknowledged
+	if c.protocol == ProtocolGraphQLTransportWS {
+		o.operations.remove(o)
+	} else {
 		o.complete()
 	}
 }
@@ -188,7 +251,7 @@ func (o *operation) execute() {
 		RequestString:  o.query,
 		VariableValues: o.variables,
 		Schema:         o.cfg.Schema,
-		// TODO: Context: nil,
+		Context:        o.ctx,
 	})
 	msg := &Message{
 		Type: MessageTypeGQLData,
@@ -202,9 +265,7 @@ func (o *operation) execute() {
 	hash := md5.Sum(data)
 	hashString := hex.EncodeToString(hash[:])
 	if hashString == o.lastMD5 {
-		go func(t time.Time) {
-			o.updated <- t
-		}(time.Now())
+		o.touch(time.Now())
 		return
 	} else {
 		o.lastMD5 = hashString
@@ -212,12 +273,30 @@ func (o *operation) execute() {
 
 	_ = o.send(msg)
 
-	go func(t time.Time) {
-		o.updated <- t
-	}(time.Now())
+	o.touch(time.Now())
 
 }
 
+// touch tells the poller when the operation was last executed. Only the most
+// recent time is kept, so it never blocks, even if no poller is running.
+func (o *operation) touch(t time.Time) {
+	select {
+	case <-o.updated:
+	default:
+	}
+	select {
+	case o.updated <- t:
+	default:
+	}
+}
+
+// cancel stops the operation's poller.
+func (o *operation) cancel() {
+	o.doneOnce.Do(func() {
+		close(o.done)
+	})
+}
+
 func (o *operation) subscribe() {
 	go o.poller()
 }
@@ -237,9 +316,15 @@ func (o *operation) poller() {
 		select {
 		case <-time.After(dt):
 			o.execute()
-			time.Sleep(interval / 2)
+			select {
+			case <-time.After(interval / 2):
+			case <-o.done:
+				return
+			}
 		case t := <-o.updated:
 			o.lastUpdate = t
+		case <-o.done:
+			return
 		}
 	}
 }
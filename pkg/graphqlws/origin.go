@@ -0,0 +1,53 @@
+package graphqlws
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// checkOrigin returns the function used by the upgrader to decide whether a
+// request's origin is allowed. With no patterns it returns nil, which makes
+// the upgrader only accept requests from the same origin.
+func checkOrigin(patterns []string) func(r *http.Request) bool {
+	if len(patterns) == 0 {
+		return nil
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			// only browsers are expected to send the header
+			return true
+		}
+		return originAllowed(origin, patterns)
+	}
+}
+
+// originAllowed reports whether an origin matches any of the patterns. A
+// pattern containing a scheme is matched against the scheme and host of the
+// origin, otherwise it is matched against the host alone. An asterisk matches
+// any part of a host, and a pattern consisting of an asterisk alone matches
+// every origin.
+func originAllowed(origin string, patterns []string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(u.Host)
+	full := strings.ToLower(u.Scheme) + "://" + host
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if pattern == "*" {
+			return true
+		}
+		target := host
+		if strings.Contains(pattern, "://") {
+			target = full
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package graphqlws
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestOriginAllowed(t *testing.T) {
+
+	tests := []struct {
+		origin   string
+		patterns []string
+		want     bool
+	}{
+		{"https://example.com", []string{"example.com"}, true},
+		{"http://example.com", []string{"example.com"}, true},
+		{"https://example.com", []string{"other.com"}, false},
+		{"https://example.com", []string{"other.com", "example.com"}, true},
+		{"https://a.example.com", []string{"*.example.com"}, true},
+		{"https://example.com", []string{"*.example.com"}, false},
+		{"https://a.b.example.com", []string{"*.example.com"}, true},
+		{"https://a.example.com", []string{"https://*.example.com"}, true},
+		{"http://a.example.com", []string{"https://*.example.com"}, false},
+		{"https://example.com:8443", []string{"example.com"}, false},
+		{"https://example.com:8443", []string{"example.com:*"}, true},
+		{"http://localhost:3000", []string{"localhost:*"}, true},
+		{"HTTPS://Example.COM", []string{"https://example.com"}, true},
+		{"https://example.com", []string{"HTTPS://EXAMPLE.COM"}, true},
+		{"https://anything.test", []string{"*"}, true},
+		{"null", []string{"*"}, false},
+		{"null", []string{"example.com"}, false},
+		{"", []string{"example.com"}, false},
+		{"https://example.com", nil, false},
+	}
+
+	for _, tt := range tests {
+		got := originAllowed(tt.origin, tt.patterns)
+		if got != tt.want {
+			t.Errorf("originAllowed('%s', %q) = %v, want %v", tt.origin, tt.patterns, got, tt.want)
+		}
+	}
+
+}
+
+func TestCheckOrigin(t *testing.T) {
+
+	if checkOrigin(nil) != nil {
+		t.Errorf("checkOrigin without patterns should defer to the same-origin check")
+	}
+
+	check := checkOrigin([]string{"https://ok.com"})
+	for origin, want := range map[string]bool{
+		"":                true,
+		"https://ok.com":  true,
+		"https://bad.com": false,
+	} {
+		r, err := http.NewRequest(http.MethodGet, "http://server/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if got := check(r); got != want {
+			t.Errorf("checkOrigin with origin '%s' = %v, want %v", origin, got, want)
+		}
+	}
+
+}
+
+func TestServerAllowedOrigins(t *testing.T) {
+
+	_, addr, stop := startTestServer(t, &ServerConfig{
+		AllowedOrigins: []string{"https://ok.com"},
+	})
+	defer stop()
+
+	for origin, allowed := range map[string]bool{
+		"":                true,
+		"https://ok.com":  true,
+		"https://bad.com": false,
+		"http://" + addr:  false,
+	} {
+		hdr := make(http.Header)
+		if origin != "" {
+			hdr.Set("Origin", origin)
+		}
+		c, err := NewClient(context.Background(), &ClientConfig{
+			Address: addr,
+			Header:  hdr,
+		})
+		if err == nil {
+			c.Close()
+		}
+		if (err == nil) != allowed {
+			t.Errorf("origin '%s': NewClient returned %v, want allowed %v", origin, err, allowed)
+		}
+	}
+
+}
+
+func TestServerSameOriginByDefault(t *testing.T) {
+
+	_, addr, stop := startTestServer(t, &ServerConfig{})
+	defer stop()
+
+	for origin, allowed := range map[string]bool{
+		"http://" + addr:   true,
+		"https://evil.com": false,
+	} {
+		hdr := make(http.Header)
+		hdr.Set("Origin", origin)
+		c, err := NewClient(context.Background(), &ClientConfig{
+			Address: addr,
+			Header:  hdr,
+		})
+		if err == nil {
+			c.Close()
+		}
+		if (err == nil) != allowed {
+			t.Errorf("origin '%s': NewClient returned %v, want allowed %v", origin, err, allowed)
+		}
+	}
+
+}
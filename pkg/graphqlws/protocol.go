@@ -0,0 +1,102 @@
+package graphqlws
+
+import "fmt"
+
+// Supported GraphQL websocket subprotocols. Clients and servers handle
+// messages using the types of the legacy graphql-ws subprotocol internally,
+// translating them on the wire when graphql-transport-ws has been negotiated.
+const (
+	ProtocolGraphQLWS          = "graphql-ws"
+	ProtocolGraphQLTransportWS = "graphql-transport-ws"
+)
+
+// defaultProtocols lists the supported subprotocols in order of preference.
+var defaultProtocols = []string{ProtocolGraphQLTransportWS, ProtocolGraphQLWS}
+
+// Close codes used by the graphql-transport-ws subprotocol.
+const (
+	closeCodeBadRequest          = 4400
+	closeCodeUnauthorized        = 4401
+	closeCodeForbidden           = 4403
+	closeCodeInitTimeout         = 4408
+	closeCodeSubscriberExists    = 4409
+	closeCodeTooManyInitRequests = 4429
+)
+
+// maxCloseReasonLength is the longest reason that fits in a close frame.
+const maxCloseReasonLength = 123
+
+func isSupportedProtocol(protocol string) bool {
+	for _, p := range defaultProtocols {
+		if p == protocol {
+			return true
+		}
+	}
+	return false
+}
+
+// closeReason truncates a reason to fit within a websocket close frame.
+func closeReason(reason string) string {
+	if len(reason) > maxCloseReasonLength {
+		reason = reason[:maxCloseReasonLength]
+	}
+	return reason
+}
+
+// transportErrors converts a legacy error payload to the list of GraphQL
+// errors expected by graphql-transport-ws.
+func transportErrors(payload interface{}) interface{} {
+	switch x := payload.(type) {
+	case []interface{}, []GQLError, GQLErrors:
+		return x
+	case error:
+		return []GQLError{{Message: x.Error()}}
+	default:
+		return []GQLError{{Message: fmt.Sprintf("%v", x)}}
+	}
+}
+
+// toTransportWS translates a legacy message to graphql-transport-ws. It
+// returns nil if the message has no equivalent and shouldn't be sent.
+func toTransportWS(m *Message) *Message {
+	out := *m
+	switch m.Type {
+	case MessageTypeGQLStart:
+		out.Type = MessageTypeGQLSubscribe
+	case MessageTypeGQLStop:
+		out.Type = MessageTypeGQLComplete
+	case MessageTypeGQLData:
+		out.Type = MessageTypeGQLNext
+	case MessageTypeGQLError:
+		out.Payload = transportErrors(m.Payload)
+	case MessageTypeGQLConnectionKeepAlive:
+		out.Type = MessageTypeGQLPing
+	case MessageTypeGQLConnectionTerminate, MessageTypeGQLConnectionError:
+		return nil
+	}
+	return &out
+}
+
+// fromTransportWS translates a graphql-transport-ws message to its legacy
+// equivalents. Because 'complete' means different things depending on who
+// sends it, the translation depends on whether the message was received by a
+// server. An error from the server also finishes its operation, so it is
+// followed by a 'complete'.
+func fromTransportWS(m *Message, server bool) []*Message {
+	out := *m
+	switch m.Type {
+	case MessageTypeGQLSubscribe:
+		out.Type = MessageTypeGQLStart
+	case MessageTypeGQLNext:
+		out.Type = MessageTypeGQLData
+	case MessageTypeGQLComplete:
+		if server {
+			out.Type = MessageTypeGQLStop
+		}
+	case MessageTypeGQLError:
+		if !server {
+			return []*Message{&out, {ID: m.ID, Type: MessageTypeGQLComplete}}
+		}
+	}
+	return []*Message{&out}
+}
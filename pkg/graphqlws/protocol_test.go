@@ -0,0 +1,181 @@
+package graphqlws
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToTransportWS(t *testing.T) {
+
+	tests := []struct {
+		in   Message
+		want *Message
+	}{
+		{
+			in:   Message{ID: "1", Type: MessageTypeGQLStart, Payload: "q"},
+			want: &Message{ID: "1", Type: MessageTypeGQLSubscribe, Payload: "q"},
+		},
+		{
+			in:   Message{ID: "1", Type: MessageTypeGQLStop},
+			want: &Message{ID: "1", Type: MessageTypeGQLComplete},
+		},
+		{
+			in:   Message{ID: "1", Type: MessageTypeGQLData, Payload: "d"},
+			want: &Message{ID: "1", Type: MessageTypeGQLNext, Payload: "d"},
+		},
+		{
+			in:   Message{ID: "1", Type: MessageTypeGQLComplete},
+			want: &Message{ID: "1", Type: MessageTypeGQLComplete},
+		},
+		{
+			in:   Message{ID: "1", Type: MessageTypeGQLError, Payload: errors.New("boom")},
+			want: &Message{ID: "1", Type: MessageTypeGQLError, Payload: []GQLError{{Message: "boom"}}},
+		},
+		{
+			in:   Message{ID: "1", Type: MessageTypeGQLError, Payload: "boom"},
+			want: &Message{ID: "1", Type: MessageTypeGQLError, Payload: []GQLError{{Message: "boom"}}},
+		},
+		{
+			in:   Message{ID: "1", Type: MessageTypeGQLError, Payload: []GQLError{{Message: "a"}}},
+			want: &Message{ID: "1", Type: MessageTypeGQLError, Payload: []GQLError{{Message: "a"}}},
+		},
+		{
+			in:   Message{Type: MessageTypeGQLConnectionKeepAlive},
+			want: &Message{Type: MessageTypeGQLPing},
+		},
+		{
+			in:   Message{Type: MessageTypeGQLConnectionInit, Payload: "p"},
+			want: &Message{Type: MessageTypeGQLConnectionInit, Payload: "p"},
+		},
+		{
+			in:   Message{Type: MessageTypeGQLConnectionAck},
+			want: &Message{Type: MessageTypeGQLConnectionAck},
+		},
+		{
+			in:   Message{Type: MessageTypeGQLConnectionTerminate},
+			want: nil,
+		},
+		{
+			in:   Message{Type: MessageTypeGQLConnectionError, Payload: "e"},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		in := tt.in
+		got := toTransportWS(&in)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("toTransportWS(%+v) = %+v, want %+v", tt.in, got, tt.want)
+		}
+		if !reflect.DeepEqual(in, tt.in) {
+			t.Errorf("toTransportWS modified its argument: %+v", in)
+		}
+	}
+
+}
+
+func TestFromTransportWS(t *testing.T) {
+
+	tests := []struct {
+		in     Message
+		server bool
+		want   []*Message
+	}{
+		{
+			in:     Message{ID: "1", Type: MessageTypeGQLSubscribe, Payload: "q"},
+			server: true,
+			want:   []*Message{{ID: "1", Type: MessageTypeGQLStart, Payload: "q"}},
+		},
+		{
+			in:     Message{ID: "1", Type: MessageTypeGQLComplete},
+			server: true,
+			want:   []*Message{{ID: "1", Type: MessageTypeGQLStop}},
+		},
+		{
+			in:     Message{ID: "1", Type: MessageTypeGQLComplete},
+			server: false,
+			want:   []*Message{{ID: "1", Type: MessageTypeGQLComplete}},
+		},
+		{
+			in:     Message{ID: "1", Type: MessageTypeGQLNext, Payload: "d"},
+			server: false,
+			want:   []*Message{{ID: "1", Type: MessageTypeGQLData, Payload: "d"}},
+		},
+		{
+			in:     Message{ID: "1", Type: MessageTypeGQLError, Payload: "e"},
+			server: false,
+			want: []*Message{
+				{ID: "1", Type: MessageTypeGQLError, Payload: "e"},
+				{ID: "1", Type: MessageTypeGQLComplete},
+			},
+		},
+		{
+			in:     Message{Type: MessageTypeGQLConnectionInit},
+			server: true,
+			want:   []*Message{{Type: MessageTypeGQLConnectionInit}},
+		},
+	}
+
+	for _, tt := range tests {
+		in := tt.in
+		got := fromTransportWS(&in, tt.server)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("fromTransportWS(%+v, %v) = %+v, want %+v", tt.in, tt.server, got, tt.want)
+		}
+	}
+
+}
+
+// TestTransportWSRoundTrip checks that every message exchanged during an
+// operation arrives as the legacy message it was sent as.
+func TestTransportWSRoundTrip(t *testing.T) {
+
+	tests := []struct {
+		m        Message
+		toServer bool
+	}{
+		{m: Message{ID: "1", Type: MessageTypeGQLStart, Payload: "q"}, toServer: true},
+		{m: Message{ID: "1", Type: MessageTypeGQLStop}, toServer: true},
+		{m: Message{Type: MessageTypeGQLConnectionInit, Payload: "p"}, toServer: true},
+		{m: Message{ID: "1", Type: MessageTypeGQLData, Payload: "d"}},
+		{m: Message{ID: "1", Type: MessageTypeGQLComplete}},
+		{m: Message{Type: MessageTypeGQLConnectionAck}},
+	}
+
+	for _, tt := range tests {
+		m := tt.m
+		got := fromTransportWS(toTransportWS(&m), tt.toServer)
+		if len(got) != 1 || !reflect.DeepEqual(*got[0], tt.m) {
+			t.Errorf("%+v arrived as %+v", tt.m, got)
+		}
+	}
+
+}
+
+func TestIsSupportedProtocol(t *testing.T) {
+
+	for _, p := range []string{ProtocolGraphQLWS, ProtocolGraphQLTransportWS} {
+		if !isSupportedProtocol(p) {
+			t.Errorf("%s should be supported", p)
+		}
+	}
+	for _, p := range []string{"", "graphql", "GRAPHQL-WS"} {
+		if isSupportedProtocol(p) {
+			t.Errorf("'%s' should not be supported", p)
+		}
+	}
+
+}
+
+func TestCloseReason(t *testing.T) {
+
+	if got := closeReason("short"); got != "short" {
+		t.Errorf("closeReason truncated a short reason to '%s'", got)
+	}
+	if got := closeReason(strings.Repeat("x", 200)); len(got) != maxCloseReasonLength {
+		t.Errorf("closeReason returned %d bytes, want %d", len(got), maxCloseReasonLength)
+	}
+
+}
@@ -0,0 +1,317 @@
+package graphqlws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ConnectionState describes the state of a Client's connection to the server.
+type ConnectionState int
+
+// Connection states
+const (
+	StateConnecting ConnectionState = iota
+	StateConnected
+	StateReconnecting
+	StateClosed
+	StateIdle
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateClosed:
+		return "closed"
+	case StateIdle:
+		return "idle"
+	}
+	return fmt.Sprintf("ConnectionState(%d)", int(s))
+}
+
+// ReconnectPolicy determines how a Client attempts to reconnect to the server
+// after losing its connection. The delay before each attempt starts at
+// InitialBackoff and is multiplied by Multiplier after each failed attempt, up
+// to MaxBackoff.
+type ReconnectPolicy struct {
+
+	// MaxAttempts is the number of consecutive failed attempts after which
+	// the client gives up and closes down. If left as zero, the client
+	// will keep trying until it is closed.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first attempt. If left as
+	// zero, a delay of 500 milliseconds is used.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the longest delay between attempts. If left as zero, a
+	// maximum of 30 seconds is used.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor the delay grows by after each failed
+	// attempt. If less than one, a multiplier of 2 is used.
+	Multiplier float64
+}
+
+const (
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+	defaultMultiplier     = 2
+)
+
+func (p *ReconnectPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	if d <= 0 {
+		d = defaultInitialBackoff
+	}
+	max := p.MaxBackoff
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+	m := p.Multiplier
+	if m < 1 {
+		m = defaultMultiplier
+	}
+
+	for i := 1; i < attempt && d < max; i++ {
+		d = time.Duration(float64(d) * m)
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// State returns the current state of the client's connection.
+func (c *Client) State() ConnectionState {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+	return c.state
+}
+
+func (c *Client) setState(state ConnectionState, err error) {
+	c.stateLock.Lock()
+	c.state = state
+	c.stateLock.Unlock()
+	if c.config.OnStateChange != nil {
+		c.config.OnStateChange(state, err)
+	}
+}
+
+// stoppingContext returns a context that is cancelled if the client is
+// closed.
+func (c *Client) stoppingContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-c.stopping:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// run dispatches messages from the connection until it is lost, then either
+// reconnects, returns to idle or closes the client down.
+func (c *Client) run(cc *clientConn) {
+	defer c.threads.Done()
+
+	for {
+		c.dispatcher(cc)
+		cc.threads.Wait()
+
+		select {
+		case <-c.stopping:
+			c.finish(nil)
+			return
+		default:
+		}
+
+		if cc.idle {
+			return
+		}
+
+		err := cc.error()
+		if err == nil {
+			err = errors.New("connection closed")
+		}
+		c.log.Error("Lost the connection to the server: %v", err)
+
+		if c.config.Lazy && c.disconnectIfIdle(err) {
+			return
+		}
+
+		if c.config.Reconnect == nil {
+			c.lost(fmt.Errorf("lost the connection to the server: %v", err))
+			return
+		}
+
+		c.setState(StateReconnecting, err)
+		cc = c.reconnect()
+		if cc == nil {
+			return
+		}
+	}
+}
+
+// reconnect dials the server until a connection is established, the policy
+// gives up or the client is closed. It returns nil if no connection could be
+// established, in which case the client has been closed down, or has returned
+// to idle if it is lazy.
+func (c *Client) reconnect() *clientConn {
+	policy := c.config.Reconnect
+
+	var err error
+	for attempt := 1; policy.MaxAttempts == 0 || attempt <= policy.MaxAttempts; attempt++ {
+		select {
+		case <-time.After(policy.backoff(attempt)):
+		case <-c.stopping:
+			c.finish(nil)
+			return nil
+		}
+
+		// a lazy client has no reason to reconnect once its operations
+		// have all been stopped
+		if c.config.Lazy && c.disconnectIfIdle(err) {
+			return nil
+		}
+
+		c.log.Info("Reconnecting to server (attempt %d)", attempt)
+		ctx, cancel := c.stoppingContext()
+		var cc *clientConn
+		cc, err = c.connect(ctx)
+		cancel()
+		if err != nil {
+			c.log.Error("Failed to reconnect: %v", err)
+			continue
+		}
+
+		c.connLock.Lock()
+		select {
+		case <-c.stopping:
+			c.connLock.Unlock()
+			cc.discard()
+			c.finish(nil)
+			return nil
+		default:
+		}
+		c.conn = cc
+		c.connLock.Unlock()
+
+		c.resume(cc)
+		c.setState(StateConnected, nil)
+		return cc
+	}
+
+	c.lost(fmt.Errorf("failed to reconnect after %d attempts: %v", policy.MaxAttempts, err))
+	return nil
+}
+
+// errOperationInterrupted is reported to queries and mutations whose
+// connection was lost before their result arrived.
+var errOperationInterrupted = errors.New("lost the connection to the server before the operation's result arrived")
+
+// resume restarts every live subscription on a new connection. Operations that
+// were stopped while the client was disconnected are finished immediately,
+// since the server has no record of them. Queries and mutations sent on the
+// lost connection are failed rather than sent again, since the server may
+// already have run them.
+func (c *Client) resume(cc *clientConn) {
+	var liveOperations []*clientOperation
+	c.opsLock.RLock()
+	for _, v := range c.operations {
+		liveOperations = append(liveOperations, v)
+	}
+	c.opsLock.RUnlock()
+
+	var resumed int
+	for _, o := range liveOperations {
+		if o.operation != "subscription" && o.interrupted(cc) {
+			if c.remove(o) {
+				o.error(errOperationInterrupted)
+				o.complete()
+				o.log.Info("Failed operation interrupted by a lost connection")
+			}
+			continue
+		}
+		if o.start(cc) {
+			resumed++
+			continue
+		}
+		if c.remove(o) {
+			o.complete()
+			o.log.Info("Cleaned up operation stopped while disconnected")
+		}
+	}
+	c.log.Info("Resumed %d operations", resumed)
+}
+
+// interrupted returns true if the operation was started on a connection
+// other than cc.
+func (o *clientOperation) interrupted(cc *clientConn) bool {
+	o.startLock.Lock()
+	defer o.startLock.Unlock()
+	return o.startedOn != nil && o.startedOn != cc
+}
+
+// takeOperations removes every live operation from the client and returns
+// them. The caller must hold the opsLock.
+func (c *Client) takeOperations() map[string]*clientOperation {
+	ops := c.operations
+	c.operations = make(map[string]*clientOperation)
+	return ops
+}
+
+// failOperations finishes each of the operations, delivering a non-nil error
+// to its ErrorCallback first.
+func failOperations(ops map[string]*clientOperation, err error) {
+	for _, o := range ops {
+		if err != nil {
+			o.error(err)
+		}
+		o.complete()
+		o.log.Info("Cleaned up operation because of a closed connection")
+	}
+}
+
+// lost handles a connection that can't be recovered. A lazy client fails its
+// live operations and returns to idle, ready to dial again on demand; any
+// other client closes down for good.
+func (c *Client) lost(err error) {
+	if !c.config.Lazy {
+		c.finish(err)
+		return
+	}
+
+	c.dialLock.Lock()
+	c.opsLock.Lock()
+	ops := c.takeOperations()
+	c.connLock.Lock()
+	c.conn = nil
+	c.connLock.Unlock()
+	c.opsLock.Unlock()
+	c.setState(StateIdle, err)
+	c.dialLock.Unlock()
+
+	failOperations(ops, err)
+}
+
+// finish closes the client down for good, finishing every live operation. A
+// non-nil error is delivered to each operation's ErrorCallback first.
+func (c *Client) finish(err error) {
+	c.opsLock.Lock()
+	c.inShutdown = true
+	ops := c.takeOperations()
+	c.opsLock.Unlock()
+
+	failOperations(ops, err)
+
+	c.setState(StateClosed, err)
+}
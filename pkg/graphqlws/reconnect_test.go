@@ -0,0 +1,382 @@
+package graphqlws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestReconnectBackoff(t *testing.T) {
+
+	ms := time.Millisecond
+	tests := []struct {
+		policy ReconnectPolicy
+		want   []time.Duration
+	}{
+		{
+			policy: ReconnectPolicy{},
+			want:   []time.Duration{500 * ms, time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			policy: ReconnectPolicy{InitialBackoff: 10 * ms, Multiplier: 3},
+			want:   []time.Duration{10 * ms, 30 * ms, 90 * ms, 270 * ms},
+		},
+		{
+			policy: ReconnectPolicy{InitialBackoff: 10 * ms, MaxBackoff: 25 * ms},
+			want:   []time.Duration{10 * ms, 20 * ms, 25 * ms, 25 * ms},
+		},
+		{
+			policy: ReconnectPolicy{InitialBackoff: 10 * ms, Multiplier: 0.5},
+			want:   []time.Duration{10 * ms, 20 * ms, 40 * ms},
+		},
+		{
+			policy: ReconnectPolicy{InitialBackoff: 10 * ms, Multiplier: 1},
+			want:   []time.Duration{10 * ms, 10 * ms, 10 * ms},
+		},
+		{
+			policy: ReconnectPolicy{InitialBackoff: time.Minute},
+			want:   []time.Duration{30 * time.Second, 30 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		for i, want := range tt.want {
+			if got := tt.policy.backoff(i + 1); got != want {
+				t.Errorf("%+v: backoff(%d) = %v, want %v", tt.policy, i+1, got, want)
+			}
+		}
+	}
+
+	// the delay stops growing at the maximum instead of overflowing
+	p := ReconnectPolicy{InitialBackoff: time.Second, MaxBackoff: time.Hour}
+	if got := p.backoff(1000); got != time.Hour {
+		t.Errorf("backoff(1000) = %v, want %v", got, time.Hour)
+	}
+
+}
+
+// flakyServer serves a Server while it is up, and records the time of every
+// request that arrives while it is down.
+type flakyServer struct {
+	t        *testing.T
+	schema   graphql.Schema
+	lock     sync.Mutex
+	srv      *Server
+	attempts []time.Time
+}
+
+func (f *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	srv := f.srv
+	if srv == nil {
+		f.attempts = append(f.attempts, time.Now())
+	}
+	f.lock.Unlock()
+	if srv == nil {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+		return
+	}
+	srv.ServeHTTP(w, r)
+}
+
+// down closes the current Server, dropping its connections, and refuses
+// requests until up is called. It returns the time the server went down.
+func (f *flakyServer) down() time.Time {
+	f.lock.Lock()
+	srv := f.srv
+	f.srv = nil
+	f.attempts = nil
+	f.lock.Unlock()
+	_ = srv.Close()
+	return time.Now()
+}
+
+func (f *flakyServer) up() {
+	schema := f.schema
+	if schema.QueryType() == nil {
+		schema = testSchema(f.t)
+	}
+	srv, err := NewServer(&ServerConfig{
+		Schema:          schema,
+		PollingInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	f.lock.Lock()
+	f.srv = srv
+	f.lock.Unlock()
+}
+
+func (f *flakyServer) attemptTimes() []time.Time {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]time.Time(nil), f.attempts...)
+}
+
+func startFlakyServer(t *testing.T, schema graphql.Schema) (*flakyServer, string, func()) {
+	f := &flakyServer{t: t, schema: schema}
+	f.up()
+	hs := httptest.NewServer(f)
+	stop := func() {
+		f.lock.Lock()
+		srv := f.srv
+		f.lock.Unlock()
+		if srv != nil {
+			_ = srv.Close()
+		}
+		hs.Close()
+	}
+	return f, strings.TrimPrefix(hs.URL, "http://"), stop
+}
+
+func TestReconnectTiming(t *testing.T) {
+
+	f, addr, stop := startFlakyServer(t, graphql.Schema{})
+	defer stop()
+
+	backoff := 20 * time.Millisecond
+	errs := make(chan error, 1)
+	c, err := NewClient(context.Background(), &ClientConfig{
+		Address: addr,
+		Reconnect: &ReconnectPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: backoff,
+			Multiplier:     2,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	sub, err := c.Subscription(&SubscriptionConfig{
+		Query:        "subscription { count }",
+		DataCallback: func(p *GQLDataPayload) {},
+		ErrorCallback: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lost := f.down()
+
+	// once every attempt has failed the subscription receives a terminal
+	// error and the client closes down
+	select {
+	case err = <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription received no error after the server went away")
+	}
+	if !strings.Contains(err.Error(), "failed to reconnect after 3 attempts") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = sub.WaitUntilFinished(ctx); err != nil {
+		t.Errorf("subscription did not finish: %v", err)
+	}
+	waitFor(t, func() bool { return c.State() == StateClosed })
+
+	attempts := f.attemptTimes()
+	if len(attempts) != 3 {
+		t.Fatalf("made %d attempts, want 3", len(attempts))
+	}
+	prev := lost
+	for i, at := range attempts {
+		want := backoff << uint(i)
+		if gap := at.Sub(prev); gap < want {
+			t.Errorf("attempt %d came %v after the last, want at least %v", i+1, gap, want)
+		}
+		prev = at
+	}
+
+}
+
+func TestReconnectResumesOperations(t *testing.T) {
+
+	f, addr, stop := startFlakyServer(t, graphql.Schema{})
+	defer stop()
+
+	var lock sync.Mutex
+	var states []ConnectionState
+	c, err := NewClient(context.Background(), &ClientConfig{
+		Address:   addr,
+		Reconnect: &ReconnectPolicy{InitialBackoff: 10 * time.Millisecond},
+		OnStateChange: func(state ConnectionState, err error) {
+			lock.Lock()
+			states = append(states, state)
+			lock.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	data := make(chan bool, 100)
+	errs := make(chan error, 1)
+	sub, err := c.Subscription(&SubscriptionConfig{
+		Query: "subscription { count }",
+		DataCallback: func(p *GQLDataPayload) {
+			select {
+			case data <- true:
+			default:
+			}
+		},
+		ErrorCallback: func(err error) {
+			errs <- err
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Stop()
+
+	select {
+	case <-data:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription received no data")
+	}
+
+	f.down()
+	waitFor(t, func() bool { return len(f.attemptTimes()) >= 2 })
+	f.up()
+
+	// drain anything delivered before the connection was lost, then
+	// expect fresh data on the new connection
+	waitFor(t, func() bool { return c.State() == StateConnected })
+	for len(data) > 0 {
+		<-data
+	}
+	select {
+	case <-data:
+	case err = <-errs:
+		t.Fatalf("subscription failed instead of resuming: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription received no data after reconnecting")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	want := []ConnectionState{StateConnecting, StateConnected, StateReconnecting, StateConnected}
+	if len(states) != len(want) {
+		t.Fatalf("states = %v, want %v", states, want)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Fatalf("states = %v, want %v", states, want)
+		}
+	}
+
+}
+
+func TestReconnectFailsInterruptedMutation(t *testing.T) {
+
+	var lock sync.Mutex
+	var runs int
+	running := make(chan bool, 1)
+	release := make(chan bool)
+	count := &graphql.Field{
+		Type: graphql.Int,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return 1, nil
+		},
+	}
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name:   "Query",
+			Fields: graphql.Fields{"count": count},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{"increment": &graphql.Field{
+				Type: graphql.Int,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					lock.Lock()
+					runs++
+					lock.Unlock()
+					running <- true
+					<-release
+					return 1, nil
+				},
+			}},
+		}),
+		Subscription: graphql.NewObject(graphql.ObjectConfig{
+			Name:   "Subscription",
+			Fields: graphql.Fields{"count": count},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, addr, stop := startFlakyServer(t, schema)
+	defer stop()
+	defer close(release)
+
+	c, err := NewClient(context.Background(), &ClientConfig{
+		Address:   addr,
+		Reconnect: &ReconnectPolicy{InitialBackoff: 10 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	sub, err := subscribe(t, c, "subscription { count }")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Stop()
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := c.Mutation(context.Background(), &MutationConfig{
+			Query: "mutation { increment }",
+		})
+		result <- err
+	}()
+
+	// drop the connection while the server is running the mutation
+	select {
+	case <-running:
+	case <-time.After(5 * time.Second):
+		t.Fatal("mutation never reached the server")
+	}
+	f.down()
+	waitFor(t, func() bool { return len(f.attemptTimes()) >= 1 })
+	f.up()
+
+	select {
+	case err = <-result:
+	case <-time.After(5 * time.Second):
+		t.Fatal("interrupted mutation never returned")
+	}
+	if err != errOperationInterrupted {
+		t.Errorf("interrupted mutation returned %v, want %v", err, errOperationInterrupted)
+	}
+
+	// the subscription is still resumed, and the mutation is not run again
+	waitFor(t, func() bool { return c.State() == StateConnected })
+	if _, err = subscribe(t, c, "subscription { count }"); err != nil {
+		t.Errorf("subscription failed after reconnecting: %v", err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if runs != 1 {
+		t.Errorf("mutation ran %d times, want 1", runs)
+	}
+
+}
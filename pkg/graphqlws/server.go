@@ -2,6 +2,7 @@ package graphqlws
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"sync"
@@ -13,7 +14,6 @@ import (
 )
 
 // TODO: PreventRepeats bool
-// TODO: ContextInjector
 
 type ServerConfig struct {
 	Logger          Logger
@@ -22,6 +22,72 @@ type ServerConfig struct {
 	PollingInterval time.Duration
 	ReadTimeout     time.Duration
 	WriteTimeout    time.Duration
+
+	// InitTimeout determines how long a client has to initialize its
+	// connection before the server closes it. If left as zero, a timeout
+	// of 10 seconds is used. A negative value disables the timeout.
+	InitTimeout time.Duration
+
+	// OnConnect is called when a client initializes its connection, with
+	// the request that opened the websocket and the payload of the client's
+	// connection_init message. It is typically used to authenticate the
+	// client. The returned context, which should be derived from ctx, is
+	// passed to OnOperation and to every resolver run on behalf of the
+	// client, so it can carry the client's identity. Returning an error
+	// rejects the connection. If nil, every connection is accepted.
+	OnConnect func(ctx context.Context, r *http.Request, initPayload interface{}) (context.Context, error)
+
+	// OnOperation is called before an operation is started, with the
+	// context returned by OnConnect. It is typically used to authorize the
+	// client's operations. Returning an error rejects the operation. If
+	// nil, every operation is allowed.
+	OnOperation func(ctx context.Context, op *OperationParams) error
+
+	// AllowedOrigins lists the origins browsers may connect from, guarding
+	// against cross-site websocket hijacking. Each entry is either an exact
+	// origin such as 'https://example.com', or a pattern where an asterisk
+	// matches any part of the host, such as 'https://*.example.com'. Entries
+	// without a scheme match the host alone, and '*' allows every origin.
+	// Requests without an Origin header, which browsers always send, are
+	// accepted. If left empty, only requests from the same origin as the
+	// server's host are accepted.
+	AllowedOrigins []string
+
+	// ReadBufferSize and WriteBufferSize determine the size of the I/O
+	// buffers used by each connection. If left as zero, buffers of 4096
+	// bytes are used.
+	ReadBufferSize  int
+	WriteBufferSize int
+
+	// EnableCompression allows per message compression to be negotiated
+	// with clients that support it.
+	EnableCompression bool
+
+	// MaxMessageSize determines the largest message, in bytes, that the
+	// server will read from a client. Clients that exceed it are
+	// disconnected. If left as zero, no limit is enforced.
+	MaxMessageSize int64
+
+	// MaxConnections limits the number of connections the server keeps open
+	// at once. Further requests are refused with a 503 Service Unavailable
+	// response until others close. If left as zero, no limit is enforced.
+	MaxConnections int
+
+	// MaxOperationsPerConnection and MaxOperations limit the number of
+	// subscriptions that a single connection, and the server as a whole,
+	// can have running at once. Subscriptions started beyond these limits
+	// are rejected with an error message. If left as zero, no limit is
+	// enforced.
+	MaxOperationsPerConnection int
+	MaxOperations              int
+
+	// MaxQueryDepth limits how deeply fields can be nested in a query, and
+	// MaxQueryComplexity limits the total number of fields it selects,
+	// counting fields selected through a fragment every time the fragment
+	// is spread. Operations exceeding either limit are rejected with an
+	// error message. If left as zero, no limit is enforced.
+	MaxQueryDepth      int
+	MaxQueryComplexity int
 }
 
 type connectionManager struct {
@@ -29,6 +95,7 @@ type connectionManager struct {
 	lock   sync.RWMutex
 	closed bool
 	conns  map[string]*connection
+	wg     sync.WaitGroup
 }
 
 func (s *Server) newConnectionManager() *connectionManager {
@@ -38,7 +105,9 @@ func (s *Server) newConnectionManager() *connectionManager {
 	}
 }
 
-func (m *connectionManager) add(c *connection) {
+// add registers a connection, returning an error if the server has been shut
+// down or has no room for it.
+func (m *connectionManager) add(c *connection) error {
 	for {
 		uid, err := uuid.NewV4()
 		if err != nil {
@@ -47,7 +116,12 @@ func (m *connectionManager) add(c *connection) {
 		c.id = uid.String()
 		m.lock.Lock()
 		if m.closed {
-			return // TODO: return error
+			m.lock.Unlock()
+			return ErrServerClosed
+		}
+		if m.cfg.MaxConnections > 0 && len(m.conns) >= m.cfg.MaxConnections {
+			m.lock.Unlock()
+			return errTooManyConnections
 		}
 		_, exists := m.conns[c.id]
 		if exists {
@@ -55,17 +129,44 @@ func (m *connectionManager) add(c *connection) {
 			continue
 		}
 		m.conns[c.id] = c
+		m.wg.Add(1)
 		m.lock.Unlock()
-		break
+		return nil
 	}
 }
 
 func (m *connectionManager) remove(c *connection) {
 	m.lock.Lock()
-	delete(m.conns, c.id)
+	if _, ok := m.conns[c.id]; ok {
+		delete(m.conns, c.id)
+		m.wg.Done()
+	}
 	m.lock.Unlock()
 }
 
+// shutdown prevents any further connections from being added and returns
+// those that are still open.
+func (m *connectionManager) shutdown() []*connection {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.closed = true
+	conns := make([]*connection, 0, len(m.conns))
+	for _, c := range m.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// wait returns a channel that is closed once every connection has finished.
+func (m *connectionManager) wait() <-chan struct{} {
+	ch := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(ch)
+	}()
+	return ch
+}
+
 func (m *connectionManager) Range(fn func(c *connection)) {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
@@ -75,6 +176,7 @@ func (m *connectionManager) Range(fn func(c *connection)) {
 }
 
 type Server struct {
+	operations  int32
 	cfg         ServerConfig
 	log         logger
 	schema      Schema
@@ -89,19 +191,73 @@ func NewServer(config *ServerConfig) (*Server, error) {
 	s.schema = NewSchema(s.cfg.Schema)
 	s.log.logger = s.cfg.Logger
 	s.upgrader = websocket.Upgrader{
-		CheckOrigin:  func(r *http.Request) bool { return true },
-		Subprotocols: []string{"graphql-ws"},
+		CheckOrigin:       checkOrigin(s.cfg.AllowedOrigins),
+		Subprotocols:      defaultProtocols,
+		ReadBufferSize:    s.cfg.ReadBufferSize,
+		WriteBufferSize:   s.cfg.WriteBufferSize,
+		EnableCompression: s.cfg.EnableCompression,
 	}
 	s.connections = s.newConnectionManager()
 	return s, nil
 }
 
+// ErrServerClosed is returned by Server.Shutdown if the server has already
+// been shut down or closed.
+var ErrServerClosed = errors.New("graphqlws: server closed")
+
+// Close is a non-blocking function that immediately closes every connection
+// and stops all of their operations. The server refuses any new connections
+// afterwards.
 func (s *Server) Close() error {
+	s.log.Info("Closing server")
+	for _, c := range s.connections.shutdown() {
+		c.kill()
+	}
 	return nil
 }
 
+// Shutdown attempts to close the server gracefully. It stops accepting new
+// connections, completes every live operation, closes every connection and
+// waits for them to finish. If the provided context expires first the
+// remaining connections are closed immediately and the context's error is
+// returned.
 func (s *Server) Shutdown(ctx context.Context) error {
-	return nil
+	if s.isClosed() {
+		return ErrServerClosed
+	}
+	s.log.Info("Shutting down server")
+	conns := s.connections.shutdown()
+	for _, c := range conns {
+		go c.shutdown()
+	}
+
+	select {
+	case <-s.connections.wait():
+		s.log.Info("Server has shut down")
+		return nil
+	case <-ctx.Done():
+		for _, c := range conns {
+			c.kill()
+		}
+		return fmt.Errorf("failed to shutdown the server: %v", ctx.Err())
+	}
+}
+
+func (s *Server) isClosed() bool {
+	s.connections.lock.RLock()
+	defer s.connections.lock.RUnlock()
+	return s.connections.closed
+}
+
+// isFull returns true if the server already has MaxConnections connections
+// open.
+func (s *Server) isFull() bool {
+	if s.cfg.MaxConnections <= 0 {
+		return false
+	}
+	s.connections.lock.RLock()
+	defer s.connections.lock.RUnlock()
+	return len(s.connections.conns) >= s.cfg.MaxConnections
 }
 
 func (s *Server) Publish(update Update) {
@@ -123,6 +279,15 @@ func (s *Server) Schema() Schema {
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.log.Info("Serving request")
+	if s.isClosed() {
+		http.Error(w, ErrServerClosed.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if s.isFull() {
+		s.log.Info("Refusing connection because the server has too many connections")
+		http.Error(w, errTooManyConnections.Error(), http.StatusServiceUnavailable)
+		return
+	}
 	ws, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		s.log.Info(fmt.Sprintf("Failed to establish websocket connection: %v", err))
@@ -132,10 +297,23 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer func() {
 		_ = ws.WriteMessage(websocket.CloseMessage, []byte{})
 	}()
-	if ws.Subprotocol() != "graphql-ws" {
-		s.log.Info("Connection does not implement the GraphQL WS protocol")
+	if !isSupportedProtocol(ws.Subprotocol()) {
+		s.log.Info("Connection does not implement a supported GraphQL websocket protocol")
+		return
+	}
+	if s.cfg.MaxMessageSize > 0 {
+		ws.SetReadLimit(s.cfg.MaxMessageSize)
+	}
+	connection := s.newConnection(ws, r)
+	defer connection.cancel()
+	err = s.connections.add(connection)
+	if err == ErrServerClosed {
+		s.log.Info("Refusing connection because the server is shutting down")
+		return
+	} else if err != nil {
+		// another connection took the last slot since the check above
+		connection.refuse(err)
 		return
 	}
-	connection := s.newConnection(ws)
 	connection.run()
 }
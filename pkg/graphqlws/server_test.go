@@ -0,0 +1,259 @@
+package graphqlws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+// testSchema returns a schema whose 'count' field, available as both a query
+// and a subscription, resolves to the number of times it has been resolved.
+func testSchema(t *testing.T) graphql.Schema {
+
+	var lock sync.Mutex
+	var n int
+	count := &graphql.Field{
+		Type: graphql.Int,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			n++
+			return n, nil
+		},
+	}
+
+	s, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name:   "Query",
+			Fields: graphql.Fields{"count": count},
+		}),
+		Subscription: graphql.NewObject(graphql.ObjectConfig{
+			Name:   "Subscription",
+			Fields: graphql.Fields{"count": count},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+// startTestServer serves a new Server with the given configuration over HTTP,
+// returning the address to dial and a function that closes both.
+func startTestServer(t *testing.T, cfg *ServerConfig) (*Server, string, func()) {
+
+	if cfg.Schema.QueryType() == nil {
+		cfg.Schema = testSchema(t)
+	}
+
+	srv, err := NewServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hs := httptest.NewServer(srv)
+	stop := func() {
+		_ = srv.Close()
+		hs.Close()
+	}
+
+	return srv, strings.TrimPrefix(hs.URL, "http://"), stop
+}
+
+// testProtocols lists the subprotocols each client test is run against.
+var testProtocols = []string{ProtocolGraphQLTransportWS, ProtocolGraphQLWS}
+
+func newTestClient(t *testing.T, addr, protocol string) *Client {
+
+	c, err := NewClient(context.Background(), &ClientConfig{
+		Address:      addr,
+		Subprotocols: []string{protocol},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// subscribe starts a subscription and waits for its first result, returning
+// the subscription or the error it was rejected with.
+func subscribe(t *testing.T, c *Client, query string) (*Subscription, error) {
+
+	data := make(chan bool, 1)
+	errs := make(chan error, 1)
+	sub, err := c.Subscription(&SubscriptionConfig{
+		Query: query,
+		DataCallback: func(p *GQLDataPayload) {
+			select {
+			case data <- true:
+			default:
+			}
+		},
+		ErrorCallback: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-data:
+		return sub, nil
+	case err = <-errs:
+		return nil, err
+	case <-time.After(5 * time.Second):
+		t.Fatalf("subscription '%s' received nothing", query)
+		return nil, nil
+	}
+}
+
+func TestServerQuery(t *testing.T) {
+
+	_, addr, stop := startTestServer(t, &ServerConfig{})
+	defer stop()
+
+	for _, protocol := range testProtocols {
+		c := newTestClient(t, addr, protocol)
+		defer c.Close()
+		res, err := c.Query(context.Background(), &QueryConfig{Query: "query { count }"})
+		if err != nil {
+			t.Fatalf("%s: %v", protocol, err)
+		}
+		if res.Data == nil {
+			t.Errorf("%s: query returned no data", protocol)
+		}
+	}
+
+}
+
+func TestServerRejectsUnsupportedProtocol(t *testing.T) {
+
+	_, addr, stop := startTestServer(t, &ServerConfig{})
+	defer stop()
+
+	_, err := NewClient(context.Background(), &ClientConfig{
+		Address:      addr,
+		Subprotocols: []string{"graphql-unknown"},
+	})
+	if err == nil {
+		t.Fatal("expected connecting with an unsupported subprotocol to fail")
+	}
+
+}
+
+// startSubscriptions starts a subscription on a new client for each protocol,
+// returning the clients and their subscriptions.
+func startSubscriptions(t *testing.T, addr string) ([]*Client, []*Subscription) {
+
+	var clients []*Client
+	var subs []*Subscription
+	for _, protocol := range testProtocols {
+		c := newTestClient(t, addr, protocol)
+		sub, err := subscribe(t, c, "subscription { count }")
+		if err != nil {
+			t.Fatalf("%s: %v", protocol, err)
+		}
+		clients = append(clients, c)
+		subs = append(subs, sub)
+	}
+
+	return clients, subs
+}
+
+func waitUntilFinished(t *testing.T, subs []*Subscription) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i, sub := range subs {
+		if err := sub.WaitUntilFinished(ctx); err != nil {
+			t.Errorf("subscription %d did not finish: %v", i, err)
+		}
+	}
+
+}
+
+func expectRefused(t *testing.T, addr string) {
+
+	resp, err := http.Get("http://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("request after shutdown returned %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+
+}
+
+func TestServerShutdown(t *testing.T) {
+
+	srv, addr, stop := startTestServer(t, &ServerConfig{PollingInterval: 10 * time.Millisecond})
+	defer stop()
+
+	clients, subs := startSubscriptions(t, addr)
+	for _, c := range clients {
+		defer c.Close()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	waitUntilFinished(t, subs)
+
+	if err := srv.Shutdown(ctx); err != ErrServerClosed {
+		t.Errorf("second Shutdown returned %v, want ErrServerClosed", err)
+	}
+	expectRefused(t, addr)
+
+}
+
+func TestServerClose(t *testing.T) {
+
+	srv, addr, stop := startTestServer(t, &ServerConfig{PollingInterval: 10 * time.Millisecond})
+	defer stop()
+
+	clients, subs := startSubscriptions(t, addr)
+	for _, c := range clients {
+		defer c.Close()
+	}
+
+	if err := srv.Close(); err != nil {
+		t.Fatal(err)
+	}
+	waitUntilFinished(t, subs)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != ErrServerClosed {
+		t.Errorf("Shutdown after Close returned %v, want ErrServerClosed", err)
+	}
+	expectRefused(t, addr)
+
+}
+
+// waitFor polls the condition until it is true, failing the test if that
+// takes too long.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
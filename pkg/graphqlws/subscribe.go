@@ -0,0 +1,184 @@
+package graphqlws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OverflowPolicy determines what happens to a value delivered by SubscribeInto
+// when the channel's buffer is full because the consumer has fallen behind.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for the consumer to make room. While waiting, no
+	// other messages are processed by the client, so a slow consumer will
+	// hold up every operation sharing the connection.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropNewest discards the value that didn't fit.
+	OverflowDropNewest
+
+	// OverflowDropOldest discards the oldest buffered value to make room.
+	OverflowDropOldest
+)
+
+// defaultSubscribeBuffer is the channel buffer used by SubscribeInto when
+// SubscribeOptions.Buffer is zero.
+const defaultSubscribeBuffer = 16
+
+// SubscribeOptions contains optional settings for SubscribeInto.
+type SubscribeOptions struct {
+
+	// OperationName selects the operation to execute if the query defines
+	// more than one.
+	OperationName string
+
+	// Buffer is the capacity of the returned channel. If left as zero, a
+	// capacity of 16 is used. A negative value makes the channel
+	// unbuffered.
+	Buffer int
+
+	// Overflow determines what happens when the channel is full.
+	Overflow OverflowPolicy
+}
+
+// GQLErrors is a list of errors returned by the server alongside the result
+// of an operation.
+type GQLErrors []GQLError
+
+func (e GQLErrors) Error() string {
+	var msgs []string
+	for i := range e {
+		msgs = append(msgs, e[i].Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// SubscriptionEvent is a single result delivered by SubscribeInto. Value holds
+// the decoded data, if the server sent any. Err is set if the server reported
+// errors, in which case it is a GQLErrors and Value may hold a partial result,
+// or if the data couldn't be decoded or the subscription failed.
+type SubscriptionEvent struct {
+	Value interface{}
+	Err   error
+}
+
+// SubscribeInto registers a GraphQL subscription and decodes each result into
+// a new value returned by newValue, which must return a pointer suitable for
+// json.Unmarshal. Results are delivered on the returned channel, which is
+// closed once the subscription finishes. Cancelling the context stops the
+// subscription.
+func (c *Client) SubscribeInto(ctx context.Context, query string, variables map[string]interface{},
+	newValue func() interface{}, opts *SubscribeOptions) (<-chan SubscriptionEvent, error) {
+
+	if newValue == nil {
+		return nil, errors.New("newValue may not be nil")
+	}
+	if opts == nil {
+		opts = new(SubscribeOptions)
+	}
+	if variables == nil {
+		variables = make(map[string]interface{})
+	}
+
+	buffer := opts.Buffer
+	if buffer == 0 {
+		buffer = defaultSubscribeBuffer
+	} else if buffer < 0 {
+		buffer = 0
+	}
+
+	out := make(chan SubscriptionEvent, buffer)
+	var lock sync.Mutex
+	var closed bool
+
+	deliver := func(e SubscriptionEvent) {
+		lock.Lock()
+		defer lock.Unlock()
+		if closed || ctx.Err() != nil {
+			return
+		}
+		switch opts.Overflow {
+		case OverflowDropNewest:
+			select {
+			case out <- e:
+			default:
+				c.log.Info("Dropping subscription value because the consumer is behind")
+			}
+		case OverflowDropOldest:
+			select {
+			case out <- e:
+				return
+			default:
+			}
+			select {
+			case <-out:
+				c.log.Info("Dropping subscription value because the consumer is behind")
+			default:
+			}
+			select {
+			case out <- e:
+			default:
+			}
+		default:
+			select {
+			case out <- e:
+			case <-ctx.Done():
+			}
+		}
+	}
+
+	sub, err := c.Subscription(&SubscriptionConfig{
+		Query:         query,
+		Variables:     variables,
+		OperationName: opts.OperationName,
+		DataCallback: func(payload *GQLDataPayload) {
+			var e SubscriptionEvent
+			if len(payload.Errors) > 0 {
+				e.Err = GQLErrors(payload.Errors)
+			}
+			if payload.Data != nil {
+				v := newValue()
+				data, err := json.Marshal(payload.Data)
+				if err == nil {
+					err = json.Unmarshal(data, v)
+				}
+				if err != nil {
+					e.Err = err
+				} else {
+					e.Value = v
+				}
+			}
+			if e.Value == nil && e.Err == nil {
+				return
+			}
+			deliver(e)
+		},
+		ErrorCallback: func(err error) {
+			deliver(SubscriptionEvent{Err: err})
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		err := sub.WaitUntilFinished(ctx)
+		if err != nil {
+			sub.Stop()
+			tctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			_ = sub.WaitUntilFinished(tctx)
+			cancel()
+		}
+		lock.Lock()
+		closed = true
+		close(out)
+		lock.Unlock()
+	}()
+
+	return out, nil
+}
@@ -0,0 +1,195 @@
+package graphqlws
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countResult struct {
+	Count int `json:"count"`
+}
+
+func newCountResult() interface{} {
+	return new(countResult)
+}
+
+// receiveCounts reads n values from the channel, failing the test on errors.
+func receiveCounts(t *testing.T, events <-chan SubscriptionEvent, n int) []int {
+	t.Helper()
+	var out []int
+	for len(out) < n {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatalf("channel closed after %d values", len(out))
+			}
+			if e.Err != nil {
+				t.Fatal(e.Err)
+			}
+			out = append(out, e.Value.(*countResult).Count)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d values, want %d", len(out), n)
+		}
+	}
+	return out
+}
+
+// waitUntilIdle waits for the server to stop all operations, and gives their
+// pollers time to return, so that a fast-polling server can be stopped cleanly.
+func waitUntilIdle(t *testing.T, srv *Server) {
+	t.Helper()
+	waitFor(t, func() bool { return atomic.LoadInt32(&srv.operations) == 0 })
+	time.Sleep(srv.cfg.PollingInterval * 4)
+}
+
+func TestSubscribeInto(t *testing.T) {
+
+	srv, addr, stop := startTestServer(t, &ServerConfig{PollingInterval: 5 * time.Millisecond})
+	defer stop()
+
+	for _, protocol := range testProtocols {
+		c := newTestClient(t, addr, protocol)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		events, err := c.SubscribeInto(ctx, "subscription { count }", nil, newCountResult, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts := receiveCounts(t, events, 3)
+		if counts[0] >= counts[1] || counts[1] >= counts[2] {
+			t.Errorf("%s: values out of order: %v", protocol, counts)
+		}
+
+		// cancelling the context stops the subscription and closes the
+		// channel
+		cancel()
+		timeout := time.After(5 * time.Second)
+		for open := true; open; {
+			select {
+			case _, open = <-events:
+			case <-timeout:
+				t.Fatalf("%s: channel not closed after cancelling", protocol)
+			}
+		}
+		c.Close()
+	}
+	waitUntilIdle(t, srv)
+
+}
+
+func TestSubscribeIntoErrors(t *testing.T) {
+
+	_, addr, stop := startTestServer(t, &ServerConfig{})
+	defer stop()
+
+	c := newTestClient(t, addr, ProtocolGraphQLTransportWS)
+	defer c.Close()
+
+	_, err := c.SubscribeInto(context.Background(), "subscription { count }", nil, nil, nil)
+	if err == nil {
+		t.Error("expected an error without newValue")
+	}
+
+	// values that can't be decoded are reported as errors
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := c.SubscribeInto(ctx, "subscription { count }", nil, func() interface{} {
+		return new(string)
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-events:
+		if e.Err == nil {
+			t.Errorf("undecodable value delivered as %v", e.Value)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("received nothing")
+	}
+
+	// as are errors from the server
+	events, err = c.SubscribeInto(ctx, "subscription { nope }", nil, newCountResult, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-events:
+		if e.Err == nil {
+			t.Errorf("invalid subscription delivered %v", e.Value)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("received nothing")
+	}
+
+}
+
+func TestSubscribeIntoOverflow(t *testing.T) {
+
+	tests := []struct {
+		overflow OverflowPolicy
+		check    func(counts []int) bool
+	}{
+		{
+			// the first values are kept and later ones discarded
+			overflow: OverflowDropNewest,
+			check: func(counts []int) bool {
+				return counts[0] == 1 && counts[1] == 2
+			},
+		},
+		{
+			// the first values are discarded in favour of later ones
+			overflow: OverflowDropOldest,
+			check: func(counts []int) bool {
+				return counts[0] > 2 && counts[1] > counts[0]
+			},
+		},
+		{
+			// nothing is discarded
+			overflow: OverflowBlock,
+			check: func(counts []int) bool {
+				return counts[0] == 1 && counts[1] == 2
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		// a new server for each policy, so that the count starts at one
+		srv, addr, stop := startTestServer(t, &ServerConfig{PollingInterval: 5 * time.Millisecond})
+		c := newTestClient(t, addr, ProtocolGraphQLTransportWS)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		events, err := c.SubscribeInto(ctx, "subscription { count }", nil, newCountResult,
+			&SubscribeOptions{Buffer: 2, Overflow: tt.overflow})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// fall behind while the server keeps publishing
+		time.Sleep(100 * time.Millisecond)
+
+		if tt.overflow != OverflowBlock {
+			// a consumer that has fallen behind doesn't hold up other
+			// operations on the connection
+			qctx, qcancel := context.WithTimeout(context.Background(), 5*time.Second)
+			_, err = c.Query(qctx, &QueryConfig{Query: "query { count }"})
+			qcancel()
+			if err != nil {
+				t.Errorf("policy %d: query failed while behind: %v", tt.overflow, err)
+			}
+		}
+
+		counts := receiveCounts(t, events, 2)
+		if !tt.check(counts) {
+			t.Errorf("policy %d: received %v", tt.overflow, counts)
+		}
+
+		cancel()
+		c.Close()
+		waitUntilIdle(t, srv)
+		stop()
+	}
+
+}
@@ -5,6 +5,7 @@ type Package struct {
 	File              PackageFragment `json:"file"`
 	Icon              PackageFragment `json:"icon"`
 	ID                string          `json:"id"`
+	Info              PackageInfo     `json:"info"`
 	Tag               string          `json:"tag"`
 	UploadedTimeplate int             `json:"uploadedTimeplate"`
 }
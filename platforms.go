@@ -0,0 +1,152 @@
+package goapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sisatech/goapi/pkg/objects"
+)
+
+// lists fetches the kernels and platforms supported by the repository's
+// environment.
+func (r *Repository) lists() (*objects.Lists, error) {
+
+	req := r.newRequest(fmt.Sprintf(`
+		query {
+			lists {
+				platforms
+				kernels {
+					release
+					source
+					type
+					version
+				}
+			}
+		}
+	`))
+
+	type responseContainer struct {
+		Lists objects.Lists `json:"lists"`
+	}
+
+	resp := new(responseContainer)
+	err := r.mgr.c.graphql.Run(r.mgr.c.ctx, req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp.Lists, nil
+}
+
+// defaults fetches the kernel and platform used by the repository's
+// environment when none are specified.
+func (r *Repository) defaults() (*objects.Defaults, error) {
+
+	req := r.newRequest(fmt.Sprintf(`
+		query {
+			defaults {
+				kernel
+				platform
+			}
+		}
+	`))
+
+	type responseContainer struct {
+		Defaults objects.Defaults `json:"defaults"`
+	}
+
+	resp := new(responseContainer)
+	err := r.mgr.c.graphql.Run(r.mgr.c.ctx, req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp.Defaults, nil
+}
+
+// Platforms lists the names of the platforms virtual machines can be
+// provisioned on. The daemon's 'lists' query only exposes platform names, not
+// the name and type pairs of objects.Platform, so names are all that can be
+// returned. They are the values accepted by ProvisionArguments.Platform.
+func (c *Client) Platforms() ([]string, error) {
+
+	l, err := c.reposMgr.Local.lists()
+	if err != nil {
+		return nil, err
+	}
+
+	return l.Platforms, nil
+}
+
+// Kernels lists the kernels available to the environment.
+func (c *Client) Kernels() ([]objects.KernelVersion, error) {
+
+	l, err := c.reposMgr.Local.lists()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]objects.KernelVersion, 0)
+	for _, k := range l.Kernels {
+		out = append(out, objects.KernelVersion{
+			Release: k.Release,
+			Source:  k.Source,
+			Type:    k.Type,
+			Version: k.Version,
+		})
+	}
+
+	return out, nil
+}
+
+// Defaults returns the kernel and platform used when none are specified.
+func (c *Client) Defaults() (*objects.Defaults, error) {
+	return c.reposMgr.Local.defaults()
+}
+
+// validate checks that the platform and kernel type requested by the
+// arguments are supported by the environment. Fields left empty are not
+// checked, as the environment's defaults will be used.
+func (m *MachinesManager) validate(args *ProvisionArguments) error {
+
+	if args.Platform == "" && args.KernelType == "" {
+		return nil
+	}
+
+	l, err := m.environment.lists()
+	if err != nil {
+		return fmt.Errorf("failed to list supported platforms and kernels: %v", err)
+	}
+
+	if args.Platform != "" {
+		var found bool
+		for _, p := range l.Platforms {
+			if p == args.Platform {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unsupported platform '%s' (supported: %s)", args.Platform,
+				strings.Join(l.Platforms, ", "))
+		}
+	}
+
+	if args.KernelType != "" {
+		types := make([]string, 0)
+		var found bool
+		for _, k := range l.Kernels {
+			if k.Type == string(args.KernelType) {
+				found = true
+				break
+			}
+			types = append(types, k.Type)
+		}
+		if !found {
+			return fmt.Errorf("unsupported kernel type '%s' (supported: %s)", args.KernelType,
+				strings.Join(types, ", "))
+		}
+	}
+
+	return nil
+}
@@ -15,6 +15,11 @@ import (
 // Push ..
 func (r *Repository) Push(args *PushArguments) (*PushOperation, error) {
 
+	err := checkGerm(args.Germ)
+	if err != nil {
+		return nil, err
+	}
+
 	if args.Injections == nil {
 		args.Injections = make([]string, 0)
 	}
@@ -49,13 +54,14 @@ func (r *Repository) Push(args *PushArguments) (*PushOperation, error) {
 		Push objects.GerminateOperation `json:"push"`
 	}
 	resp := new(responseContainer)
-	err := r.mgr.c.graphql.Run(r.mgr.c.ctx, req, &resp)
+	err = r.mgr.c.graphql.Run(r.mgr.c.ctx, req, &resp)
 	if err != nil {
 		return nil, err
 	}
 
 	out := new(PushOperation)
 	out.graphql = r.mgr.c.graphql
+	out.http = r.mgr.c.http
 	out.jobID = resp.Push.Job.ID
 	out.uri = resp.Push.URI
 
@@ -80,6 +86,7 @@ type PushArguments struct {
 // PushOperation ..
 type PushOperation struct {
 	graphql *graphql.Client
+	http    *http.Client
 	jobID   string
 	uri     string
 	host    string
@@ -161,7 +168,7 @@ func (p *PushOperation) Inject(key string, itype InjectionType, value io.Reader,
 		}
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := p.http.Do(req)
 	if err != nil {
 		return err
 	}
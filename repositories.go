@@ -2,6 +2,7 @@ package goapi
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/machinebox/graphql"
@@ -17,30 +18,18 @@ type RepositoriesManager struct {
 // Connections lists all connected repositories.
 func (r *RepositoriesManager) Connections() ([]Repository, error) {
 
-	req := graphql.NewRequest(fmt.Sprintf(`
-		query {
-			listNodes {
-				name
-				host
-			}
-		}
-	`))
-
-	type responseContanier struct {
-		ListNodes []objects.Node `json:"listNodes"`
-	}
-	resp := new(responseContanier)
-	err := r.c.graphql.Run(r.c.ctx, req, &resp)
+	nodes, err := r.listNodes()
 	if err != nil {
 		return nil, err
 	}
 
 	out := make([]Repository, 0)
-	for _, n := range resp.ListNodes {
+	for _, n := range nodes {
 		r := &Repository{
-			mgr:  r,
-			name: n.Name,
-			host: n.Host,
+			mgr:      r,
+			name:     n.Name,
+			host:     n.Host,
+			nodeType: n.Type,
 		}
 
 		err = r.init()
@@ -54,77 +43,215 @@ func (r *RepositoriesManager) Connections() ([]Repository, error) {
 	return out, nil
 }
 
-// Connect establishes a new repository connection.
-func (r *RepositoriesManager) Connect(name, addr, key string, skipInsecureCheck bool) error {
+// ConnectOptions contains the fields used when connecting to, or updating the
+// connection to, a remote repository.
+type ConnectOptions struct {
+
+	// Credentials are used to authenticate with the repository.
+	Credentials string
+
+	// CredentialsType describes how Credentials should be presented to the
+	// repository. If left empty the environment's default is used.
+	CredentialsType string
+
+	// CABundle is a PEM encoded bundle of certificate authorities trusted
+	// when verifying the repository's certificate, in addition to the
+	// system's.
+	CABundle string
 
+	// InsecureSkipVerify disables verification of the repository's
+	// certificate. If left nil, new connections verify the certificate and
+	// existing connections keep their current setting.
+	InsecureSkipVerify *bool
+
+	// NodeType is the type of repository node being connected to. If left
+	// empty the environment will detect it.
+	NodeType string
+}
+
+// nodeArguments builds the variable declarations and argument list of a node
+// mutation. Values are passed as variables so that credentials and
+// certificates never need escaping.
+func nodeArguments(mutation string, vars map[string]interface{}) (string, string, error) {
+
+	keys := make([]string, 0)
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	decls := make([]string, 0)
 	args := make([]string, 0)
-	args = append(args, fmt.Sprintf(`name: "%s"`, name))
-	args = append(args, fmt.Sprintf(`addr: "%s"`, addr))
-	if key != "" {
-		args = append(args, fmt.Sprintf(`credentials: "%s"`, key))
+	for _, k := range keys {
+		t, ok := nodeArgumentTypes[mutation][k]
+		if !ok {
+			return "", "", fmt.Errorf("unknown argument '%s' to %s", k, mutation)
+		}
+		decls = append(decls, fmt.Sprintf("$%s: %s", k, t))
+		args = append(args, fmt.Sprintf("%s: $%s", k, k))
 	}
-	args = append(args, fmt.Sprintf(`insecureSkipVerify: %v`, skipInsecureCheck))
-	argsStr := fmt.Sprintf("(%s)", strings.Join(args, ", "))
 
+	return strings.Join(decls, ", "), strings.Join(args, ", "), nil
+}
+
+// nodeArgumentTypes mirrors the argument types of the node mutations in the
+// environment's schema, since variables must be declared with exactly the type
+// of the argument they are passed to.
+var nodeArgumentTypes = map[string]map[string]string{
+	"newNode": {
+		"name":               "String!",
+		"addr":               "String!",
+		"credentials":        "String",
+		"credentialsType":    "String",
+		"caBundle":           "String",
+		"insecureSkipVerify": "Boolean",
+		"type":               "String",
+	},
+	"updateNode": {
+		"name":               "String!",
+		"addr":               "String",
+		"credentials":        "String",
+		"credentialsType":    "String",
+		"caBundle":           "String",
+		"insecureSkipVerify": "Boolean",
+		"type":               "String",
+	},
+}
+
+func (o *ConnectOptions) vars(vars map[string]interface{}) {
+	if o.Credentials != "" {
+		vars["credentials"] = o.Credentials
+	}
+	if o.CredentialsType != "" {
+		vars["credentialsType"] = o.CredentialsType
+	}
+	if o.CABundle != "" {
+		vars["caBundle"] = o.CABundle
+	}
+	if o.NodeType != "" {
+		vars["type"] = o.NodeType
+	}
+	if o.InsecureSkipVerify != nil {
+		vars["insecureSkipVerify"] = *o.InsecureSkipVerify
+	}
+}
+
+// mutateNode runs a mutation that returns a node, and returns a Repository for
+// that node.
+func (r *RepositoriesManager) mutateNode(mutation string, vars map[string]interface{}) (*Repository, error) {
+
+	decls, args, err := nodeArguments(mutation, vars)
+	if err != nil {
+		return nil, err
+	}
 	req := graphql.NewRequest(fmt.Sprintf(`
-		mutation {
-			newNode%s{
+		mutation (%s) {
+			%s(%s) {
 				name
+				host
+				type
 			}
 		}
-	`, argsStr))
+	`, decls, mutation, args))
+	for k, v := range vars {
+		req.Var(k, v)
+	}
 
-	type responseContainer struct {
-		NewNode objects.Node `json:"newNode"`
+	resp := make(map[string]objects.Node)
+	err = r.c.graphql.Run(r.c.ctx, req, &resp)
+	if err != nil {
+		return nil, err
 	}
+	r.InvalidateCache()
 
-	resp := new(responseContainer)
-	err := r.c.graphql.Run(r.c.ctx, req, &resp)
+	n := resp[mutation]
+	out := &Repository{
+		mgr:      r,
+		name:     n.Name,
+		host:     n.Host,
+		nodeType: n.Type,
+	}
+	err = out.init()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return out, nil
 }
 
-// Get a specific repository.
-func (r *RepositoriesManager) Get(name string) (*Repository, error) {
+// Connect establishes a new repository connection.
+func (r *RepositoriesManager) Connect(name, addr string, opts *ConnectOptions) (*Repository, error) {
 
-	req := graphql.NewRequest(fmt.Sprintf(`
-		query {
-			listNodes {
-				name
-				host
-			}
-		}
-	`))
+	if opts == nil {
+		opts = new(ConnectOptions)
+	}
 
-	type responseContainer struct {
-		ListNodes []objects.Node `json:"listNodes"`
+	vars := map[string]interface{}{
+		"name": name,
+		"addr": addr,
 	}
-	resp := new(responseContainer)
-	err := r.c.graphql.Run(r.c.ctx, req, &resp)
+	opts.vars(vars)
+
+	return r.mutateNode("newNode", vars)
+}
+
+// UpdateConnection changes the settings of an existing repository connection
+// without disconnecting it. Only the address and options that are set are
+// changed: addr if non-empty, string options if non-empty and
+// InsecureSkipVerify if non-nil.
+func (r *RepositoriesManager) UpdateConnection(name, addr string, opts *ConnectOptions) (*Repository, error) {
+
+	if opts == nil {
+		opts = new(ConnectOptions)
+	}
+
+	vars := map[string]interface{}{
+		"name": name,
+	}
+	if addr != "" {
+		vars["addr"] = addr
+	}
+	opts.vars(vars)
+
+	return r.mutateNode("updateNode", vars)
+}
+
+// RotateCredentials replaces the credentials of an existing repository
+// connection, leaving its other settings untouched.
+func (r *RepositoriesManager) RotateCredentials(name, credentials, credentialsType string) error {
+
+	vars := map[string]interface{}{
+		"name":        name,
+		"credentials": credentials,
+	}
+	if credentialsType != "" {
+		vars["credentialsType"] = credentialsType
+	}
+
+	_, err := r.mutateNode("updateNode", vars)
+	return err
+}
+
+// Get a specific repository.
+func (r *RepositoriesManager) Get(name string) (*Repository, error) {
+
+	n, err := r.lookupNode(name)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, n := range resp.ListNodes {
-		if n.Name == name {
-			out := &Repository{
-				mgr:  r,
-				name: n.Name,
-				host: n.Host,
-			}
-			err = out.init()
-			if err != nil {
-				return nil, err
-			}
-
-			return out, nil
-		}
+	out := &Repository{
+		mgr:      r,
+		name:     n.Name,
+		host:     n.Host,
+		nodeType: n.Type,
+	}
+	err = out.init()
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("could not repository '%s'", name)
+	return out, nil
 }
 
 // Disconnect destroys the Repository object and unregisters it from the current
@@ -142,5 +269,10 @@ func (r *RepositoriesManager) Disconnect(name string) error {
 	}
 	resp := new(responseContainer)
 	err := r.c.graphql.Run(r.c.ctx, req, &resp)
-	return err
+	if err != nil {
+		return err
+	}
+	r.InvalidateCache()
+
+	return nil
 }
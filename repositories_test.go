@@ -0,0 +1,57 @@
+package goapi
+
+import "testing"
+
+func TestConnectOptionsVars(t *testing.T) {
+
+	vars := make(map[string]interface{})
+	(&ConnectOptions{Credentials: "secret"}).vars(vars)
+	if _, ok := vars["insecureSkipVerify"]; ok {
+		t.Errorf("insecureSkipVerify sent although it was not set: %v", vars)
+	}
+	if vars["credentials"] != "secret" || len(vars) != 1 {
+		t.Errorf("unexpected vars: %v", vars)
+	}
+
+	skip := false
+	vars = make(map[string]interface{})
+	(&ConnectOptions{InsecureSkipVerify: &skip}).vars(vars)
+	if v, ok := vars["insecureSkipVerify"]; !ok || v != false {
+		t.Errorf("insecureSkipVerify not sent although it was set: %v", vars)
+	}
+
+}
+
+func TestNodeArguments(t *testing.T) {
+
+	vars := map[string]interface{}{
+		"name":               "repo",
+		"addr":               "https://example.com",
+		"insecureSkipVerify": true,
+	}
+
+	decls, args, err := nodeArguments("newNode", vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "$addr: String!, $insecureSkipVerify: Boolean, $name: String!"; decls != want {
+		t.Errorf("decls = '%s', want '%s'", decls, want)
+	}
+	if want := "addr: $addr, insecureSkipVerify: $insecureSkipVerify, name: $name"; args != want {
+		t.Errorf("args = '%s', want '%s'", args, want)
+	}
+
+	decls, _, err = nodeArguments("updateNode", vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "$addr: String, $insecureSkipVerify: Boolean, $name: String!"; decls != want {
+		t.Errorf("decls = '%s', want '%s'", decls, want)
+	}
+
+	_, _, err = nodeArguments("newNode", map[string]interface{}{"bogus": 1})
+	if err == nil {
+		t.Errorf("expected error for unknown argument")
+	}
+
+}
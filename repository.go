@@ -15,6 +15,7 @@ type Repository struct {
 	mgr      *RepositoriesManager
 	name     string
 	host     string
+	nodeType string
 	subsDoer *graphqlws.Client
 	hdr      http.Header
 }
@@ -39,6 +40,21 @@ func (r *Repository) init() error {
 	return nil
 }
 
+// Name returns the name the repository is registered under.
+func (r *Repository) Name() string {
+	return r.name
+}
+
+// Host returns the address of the repository.
+func (r *Repository) Host() string {
+	return r.host
+}
+
+// Type returns the type of the repository node.
+func (r *Repository) Type() string {
+	return r.nodeType
+}
+
 func (r *Repository) newRequest(str string) *graphql.Request {
 	req := graphql.NewRequest(str)
 	req.Header = r.hdr
@@ -147,7 +163,7 @@ func (r *Repository) Download(bucket, app, version string, w io.Writer) error {
 		return err
 	}
 
-	res, err := http.DefaultClient.Do(re)
+	res, err := r.mgr.c.http.Do(re)
 	if err != nil {
 		return err
 	}
@@ -281,6 +297,27 @@ func (r *Repository) ListBuckets(cursor *Cursor) (*BucketList, error) {
 	return out, nil
 }
 
+// allBuckets walks every page of the repository's bucket list.
+func (r *Repository) allBuckets() ([]Bucket, error) {
+
+	out := make([]Bucket, 0)
+	err := walkPages(func(curs *Cursor) (objects.PageInfo, error) {
+		list, err := r.ListBuckets(curs)
+		if err != nil {
+			return objects.PageInfo{}, err
+		}
+		for _, item := range list.Items {
+			out = append(out, item.Bucket)
+		}
+		return list.PageInfo, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
 // // ListApps returns a list of apps within a bucket.
 // func (r *Repository) ListApps(bucket string, cursor *Cursor) ([]string, error) {
 //
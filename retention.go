@@ -0,0 +1,205 @@
+package goapi
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RetentionPolicy describes which versions of an app should be kept. A version
+// is kept if any rule protects it; every other version is considered expired.
+// At least one rule must be set: the zero value protects nothing, so it is
+// rejected rather than expiring every version.
+type RetentionPolicy struct {
+
+	// KeepLast is the number of most recently uploaded versions that are
+	// always kept.
+	KeepLast int
+
+	// KeepTagged causes any version with a tag to always be kept.
+	KeepTagged bool
+
+	// MaxAge, if non-zero, causes versions uploaded more recently than
+	// MaxAge to always be kept. Combined with KeepTagged this expresses
+	// "delete untagged versions older than MaxAge".
+	MaxAge time.Duration
+}
+
+func (p *RetentionPolicy) validate() error {
+	if p.KeepLast < 0 {
+		return fmt.Errorf("retention policy KeepLast may not be negative")
+	}
+	if p.MaxAge < 0 {
+		return fmt.Errorf("retention policy MaxAge may not be negative")
+	}
+	if p.KeepLast == 0 && !p.KeepTagged && p.MaxAge == 0 {
+		return fmt.Errorf("retention policy sets no rules and would delete every version")
+	}
+	return nil
+}
+
+// RetentionDecision records the verdict of a RetentionPolicy on a single
+// version, along with a human readable reason.
+type RetentionDecision struct {
+	Version Version
+	Tag     string
+	Reason  string
+}
+
+// RetentionReport is the result of evaluating a RetentionPolicy. Evaluating a
+// policy never modifies the repository, so a report can be used as a dry-run
+// and then passed to Apply.
+type RetentionReport struct {
+	Policy  RetentionPolicy
+	Kept    []RetentionDecision
+	Expired []RetentionDecision
+}
+
+// RetentionFailure records a version that could not be deleted.
+type RetentionFailure struct {
+	Version Version
+	Err     error
+}
+
+// RetentionResult contains the outcome of applying a RetentionReport.
+type RetentionResult struct {
+	Deleted []Version
+	Failed  []RetentionFailure
+}
+
+// EvaluateRetention applies the policy to every version of the App and reports
+// what would be kept and what would be deleted. Nothing is deleted.
+func (a *App) EvaluateRetention(policy RetentionPolicy) (*RetentionReport, error) {
+
+	err := policy.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RetentionReport{
+		Policy:  policy,
+		Kept:    make([]RetentionDecision, 0),
+		Expired: make([]RetentionDecision, 0),
+	}
+
+	err = report.evaluate(a, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// EvaluateRetention applies the policy to every app within the Bucket. Rules
+// such as KeepLast are evaluated per app. Nothing is deleted.
+func (b *Bucket) EvaluateRetention(policy RetentionPolicy) (*RetentionReport, error) {
+
+	err := policy.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	apps, err := b.allApps()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RetentionReport{
+		Policy:  policy,
+		Kept:    make([]RetentionDecision, 0),
+		Expired: make([]RetentionDecision, 0),
+	}
+
+	now := time.Now()
+	for i := range apps {
+		err = report.evaluate(&apps[i], now)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+func (r *RetentionReport) evaluate(a *App, now time.Time) error {
+
+	versions, err := a.allVersions()
+	if err != nil {
+		return fmt.Errorf("failed to list versions of '%s': %v", a.Name(), err)
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].UploadedTime().After(versions[j].UploadedTime())
+	})
+
+	for i, v := range versions {
+		d := RetentionDecision{
+			Version: v,
+			Tag:     v.tag,
+		}
+
+		switch {
+		case i < r.Policy.KeepLast:
+			d.Reason = fmt.Sprintf("within the %d most recent versions", r.Policy.KeepLast)
+		case r.Policy.KeepTagged && v.tag != "":
+			d.Reason = fmt.Sprintf("tagged '%s'", v.tag)
+		case r.Policy.MaxAge != 0 && now.Sub(v.UploadedTime()) < r.Policy.MaxAge:
+			d.Reason = fmt.Sprintf("uploaded less than %v ago", r.Policy.MaxAge)
+		}
+
+		if d.Reason != "" {
+			r.Kept = append(r.Kept, d)
+			continue
+		}
+
+		d.Reason = "not protected by any retention rule"
+		r.Expired = append(r.Expired, d)
+	}
+
+	return nil
+}
+
+// Apply deletes every expired version in the report, waiting at least
+// 'interval' between consecutive deletions to avoid overloading the
+// repository. Failed deletions are recorded in the result rather than aborting
+// the run. An error is only returned if the context is cancelled, in which case
+// the partial result is returned alongside it. Reports whose policy sets no
+// rules are refused.
+func (r *RetentionReport) Apply(ctx context.Context, interval time.Duration) (*RetentionResult, error) {
+
+	err := r.Policy.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	out := &RetentionResult{
+		Deleted: make([]Version, 0),
+		Failed:  make([]RetentionFailure, 0),
+	}
+
+	for i, d := range r.Expired {
+		if i > 0 && interval > 0 {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return out, ctx.Err()
+			}
+		} else if ctx.Err() != nil {
+			return out, ctx.Err()
+		}
+
+		v := d.Version
+		err = v.Delete()
+		if err != nil {
+			out.Failed = append(out.Failed, RetentionFailure{
+				Version: v,
+				Err:     err,
+			})
+			continue
+		}
+		out.Deleted = append(out.Deleted, v)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,43 @@
+package goapi
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRetentionPolicyValidate(t *testing.T) {
+
+	tests := []struct {
+		policy RetentionPolicy
+		err    bool
+	}{
+		{policy: RetentionPolicy{}, err: true},
+		{policy: RetentionPolicy{KeepLast: -1, KeepTagged: true}, err: true},
+		{policy: RetentionPolicy{MaxAge: -time.Hour, KeepTagged: true}, err: true},
+		{policy: RetentionPolicy{KeepLast: 1}},
+		{policy: RetentionPolicy{KeepTagged: true}},
+		{policy: RetentionPolicy{MaxAge: time.Hour}},
+	}
+
+	for _, tt := range tests {
+		err := tt.policy.validate()
+		if (err != nil) != tt.err {
+			t.Errorf("%+v: validate() = %v, want error %v", tt.policy, err, tt.err)
+		}
+	}
+
+}
+
+func TestRetentionApplyRefusesEmptyPolicy(t *testing.T) {
+
+	report := &RetentionReport{
+		Expired: []RetentionDecision{{Version: Version{id: "v1"}}},
+	}
+
+	res, err := report.Apply(context.Background(), 0)
+	if err == nil {
+		t.Fatalf("expected Apply to refuse an empty policy, got %+v", res)
+	}
+
+}
@@ -0,0 +1,335 @@
+package goapi
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sisatech/goapi/pkg/objects"
+)
+
+// defaultSearchConcurrency is the number of apps searched in parallel when a
+// SearchQuery doesn't specify its own Concurrency.
+const defaultSearchConcurrency = 4
+
+// SearchQuery describes the filters applied by Repository.Search. Every filter
+// is optional, and a zero SearchQuery matches every app in the repository.
+// Name filters accept the shell patterns understood by path.Match.
+type SearchQuery struct {
+
+	// Bucket filters buckets by name. A pattern without wildcards is
+	// looked up directly rather than by listing every bucket.
+	Bucket string
+
+	// App filters apps by name. If both Bucket and App contain no
+	// wildcards the app is looked up directly rather than by listing.
+	App string
+
+	// Tag filters versions by tag. A pattern without wildcards is resolved
+	// by the repository with one lookup per app rather than by listing
+	// every version. The repository can't tell a missing tag apart from
+	// any other failure, so when Bucket or App is a pattern a failed
+	// lookup is treated as no match. When both name a single app, a failed
+	// lookup falls back to listing that app's versions, so that genuine
+	// errors are still reported.
+	Tag string
+
+	// UploadedAfter and UploadedBefore limit versions to those uploaded
+	// within a time range. Either may be left as the zero time.
+	UploadedAfter  time.Time
+	UploadedBefore time.Time
+
+	// Metadata filters versions by the information in their package
+	// configuration. Keys are any of "app", "author", "description",
+	// "summary", "url", "version" and "kernel"; values are patterns.
+	Metadata map[string]string
+
+	// Concurrency is the number of apps searched in parallel. Defaults to
+	// 4 if left as zero.
+	Concurrency int
+}
+
+// SearchResult is a single match returned by Repository.Search. If the query
+// contained no version filters (Tag, upload time or Metadata) each result
+// identifies an app and Version is nil; otherwise each result identifies a
+// matching version of the app.
+type SearchResult struct {
+	App     *App
+	Version *Version
+}
+
+func (q *SearchQuery) filtersVersions() bool {
+	return q.Tag != "" || !q.UploadedAfter.IsZero() || !q.UploadedBefore.IsZero() ||
+		len(q.Metadata) != 0
+}
+
+func (q *SearchQuery) validate() error {
+
+	for _, p := range []string{q.Bucket, q.App, q.Tag} {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid search pattern '%s': %v", p, err)
+		}
+	}
+
+	for k, p := range q.Metadata {
+		if _, ok := metadataField(&objects.PackageConfig{}, k); !ok {
+			return fmt.Errorf("unsupported metadata field '%s'", k)
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid search pattern '%s': %v", p, err)
+		}
+	}
+
+	if !q.UploadedAfter.IsZero() && !q.UploadedBefore.IsZero() &&
+		q.UploadedBefore.Before(q.UploadedAfter) {
+		return fmt.Errorf("search upload time range ends before it begins")
+	}
+
+	return nil
+}
+
+func metadataField(cfg *objects.PackageConfig, key string) (string, bool) {
+	switch key {
+	case "app":
+		return cfg.Info.App, true
+	case "author":
+		return cfg.Info.Author, true
+	case "description":
+		return cfg.Info.Description, true
+	case "summary":
+		return cfg.Info.Summary, true
+	case "url":
+		return cfg.Info.URL, true
+	case "version":
+		return cfg.Info.Version, true
+	case "kernel":
+		return cfg.Info.Kernel, true
+	}
+	return "", false
+}
+
+// isLiteral returns true if the pattern contains no wildcards, meaning it can
+// only ever match a single name.
+func isLiteral(pattern string) bool {
+	return pattern != "" && !strings.ContainsAny(pattern, `*?[\`)
+}
+
+// matches reports whether the name matches the pattern. An empty pattern
+// matches everything. Patterns are validated before use, so errors can be
+// ignored.
+func matches(pattern, name string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// Search finds apps, or versions of apps, within the repository that match the
+// query. Exact names are resolved by the repository wherever possible; the
+// remainder of the repository is walked concurrently and filtered client-side.
+func (r *Repository) Search(q *SearchQuery) ([]SearchResult, error) {
+
+	if q == nil {
+		q = new(SearchQuery)
+	}
+
+	err := q.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	apps, err := r.searchApps(q)
+	if err != nil {
+		return nil, err
+	}
+
+	exact := isLiteral(q.Bucket) && isLiteral(q.App)
+
+	concurrency := q.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultSearchConcurrency
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+	out := make([]SearchResult, 0)
+	sem := make(chan bool, concurrency)
+
+	for i := range apps {
+		if !q.filtersVersions() {
+			out = append(out, SearchResult{App: apps[i]})
+			continue
+		}
+
+		wg.Add(1)
+		sem <- true
+		go func(a *App) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			versions, err := searchVersions(a, q, exact)
+
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to search '%s': %v", a.Germ(), err)
+				}
+				return
+			}
+			for j := range versions {
+				out = append(out, SearchResult{
+					App:     a,
+					Version: &versions[j],
+				})
+			}
+		}(apps[i])
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.App.bucket.Name() != b.App.bucket.Name() {
+			return a.App.bucket.Name() < b.App.bucket.Name()
+		}
+		if a.App.Name() != b.App.Name() {
+			return a.App.Name() < b.App.Name()
+		}
+		if a.Version == nil || b.Version == nil {
+			return false
+		}
+		return a.Version.UploadedTime().After(b.Version.UploadedTime())
+	})
+
+	return out, nil
+}
+
+func (r *Repository) searchApps(q *SearchQuery) ([]*App, error) {
+
+	var buckets []Bucket
+	if isLiteral(q.Bucket) {
+		b, err := r.GetBucket(q.Bucket)
+		if err != nil {
+			return nil, err
+		}
+		buckets = []Bucket{*b}
+	} else {
+		all, err := r.allBuckets()
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range all {
+			if matches(q.Bucket, b.Name()) {
+				buckets = append(buckets, b)
+			}
+		}
+	}
+
+	out := make([]*App, 0)
+	for i := range buckets {
+		b := &buckets[i]
+		// A missing app is only an error if the query names exactly one
+		// bucket; otherwise most buckets can be expected not to have it.
+		if isLiteral(q.App) && isLiteral(q.Bucket) {
+			a, err := b.App(q.App)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, a)
+			continue
+		}
+
+		apps, err := b.allApps()
+		if err != nil {
+			return nil, err
+		}
+		for j := range apps {
+			if matches(q.App, apps[j].Name()) {
+				out = append(out, &apps[j])
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func searchVersions(a *App, q *SearchQuery, exact bool) ([]Version, error) {
+
+	withConfig := len(q.Metadata) != 0
+
+	var items []VersionListItem
+	if isLiteral(q.Tag) {
+		// The repository resolves tags as version references, but an app
+		// without the tag produces an error indistinguishable from any
+		// other. Across many apps, most lack the tag and listing each of
+		// them would cost far more than the lookup saves, so a failure is
+		// only double-checked by listing when the query names one app.
+		v, err := a.Version(q.Tag)
+		if err != nil && !exact {
+			return nil, nil
+		}
+		if err == nil {
+			if v.tag != q.Tag {
+				return nil, nil
+			}
+			item := VersionListItem{Version: *v}
+			if withConfig {
+				info, err := v.Info()
+				if err != nil {
+					return nil, err
+				}
+				item.config = &info.ConfigurationDetails
+			}
+			items = []VersionListItem{item}
+		}
+	}
+	if items == nil {
+		var err error
+		items, err = a.allVersionItems(withConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([]Version, 0)
+	for _, item := range items {
+		v := item.Version
+		if q.Tag != "" && (v.tag == "" || !matches(q.Tag, v.tag)) {
+			continue
+		}
+		if !q.UploadedAfter.IsZero() && v.UploadedTime().Before(q.UploadedAfter) {
+			continue
+		}
+		if !q.UploadedBefore.IsZero() && !v.UploadedTime().Before(q.UploadedBefore) {
+			continue
+		}
+		if withConfig && !metadataMatches(q.Metadata, item.config) {
+			continue
+		}
+
+		out = append(out, v)
+	}
+
+	return out, nil
+}
+
+func metadataMatches(filters map[string]string, cfg *objects.PackageConfig) bool {
+	for k, p := range filters {
+		val, _ := metadataField(cfg, k)
+		if !matches(p, val) {
+			return false
+		}
+	}
+	return true
+}
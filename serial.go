@@ -0,0 +1,172 @@
+package goapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sisatech/goapi/pkg/objects"
+)
+
+// TailOptions contains fields used to customize VirtualMachine.Tail.
+type TailOptions struct {
+
+	// FromBeginning causes the entire serial log to be streamed. If false,
+	// only output produced after Tail is called is streamed.
+	FromBeginning bool
+
+	// PollInterval is how long to wait before asking for more output once
+	// the stream has caught up with the machine. Defaults to one second.
+	PollInterval time.Duration
+
+	// MaxRetries is the number of consecutive failed requests tolerated
+	// before the stream is closed with an error. Failed requests are
+	// retried with an increasing delay, resuming from the last chunk
+	// received. If zero, requests are retried until the context ends.
+	MaxRetries int
+}
+
+type serialChunk struct {
+	ID   string
+	Data string
+	More bool
+}
+
+func (v *VirtualMachine) serialChunk(ctx context.Context, after string) (*serialChunk, error) {
+
+	var args string
+	if after != "" {
+		args = fmt.Sprintf(`(after: "%s")`, after)
+	}
+
+	req := v.mgr.environment.newRequest(fmt.Sprintf(`
+		query {
+			vm(id: "%s") {
+				serial%s {
+					id
+					data
+					more
+				}
+			}
+		}
+	`, v.ID(), args))
+
+	type responseContainer struct {
+		VM objects.VM `json:"vm"`
+	}
+
+	resp := new(responseContainer)
+	err := v.mgr.c.graphql.Run(ctx, req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &serialChunk{
+		ID:   resp.VM.Serial.ID,
+		Data: resp.VM.Serial.Data,
+		More: resp.VM.Serial.More,
+	}, nil
+}
+
+type serialReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (r *serialReader) Close() error {
+	r.cancel()
+	return r.PipeReader.Close()
+}
+
+// Tail follows the virtual machine serial output. The returned reader streams
+// output until the context ends or the reader is closed, transparently
+// retrying requests that fail along the way. Reads return the context's error
+// once it ends.
+func (v *VirtualMachine) Tail(ctx context.Context, opts *TailOptions) (io.ReadCloser, error) {
+
+	if opts == nil {
+		opts = new(TailOptions)
+	}
+	interval := opts.PollInterval
+	if interval == 0 {
+		interval = time.Second
+	}
+
+	var cursor string
+	if !opts.FromBeginning {
+		// skip everything that has already been written
+		for {
+			chunk, err := v.serialChunk(ctx, cursor)
+			if err != nil {
+				return nil, err
+			}
+			cursor = chunk.ID
+			if !chunk.More {
+				break
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+
+	go func() {
+		defer cancel()
+
+		var failures int
+		for {
+			chunk, err := v.serialChunk(ctx, cursor)
+			if err != nil {
+				failures++
+				if opts.MaxRetries != 0 && failures > opts.MaxRetries {
+					pw.CloseWithError(fmt.Errorf("failed to read serial output: %v", err))
+					return
+				}
+				backoff := time.Duration(failures) * interval
+				if backoff > 30*time.Second {
+					backoff = 30 * time.Second
+				}
+				select {
+				case <-time.After(backoff):
+					continue
+				case <-ctx.Done():
+					pw.CloseWithError(ctx.Err())
+					return
+				}
+			}
+			failures = 0
+
+			if chunk.Data != "" {
+				_, err = io.WriteString(pw, chunk.Data)
+				if err != nil {
+					// the reader has been closed
+					return
+				}
+			}
+			if chunk.ID != "" {
+				cursor = chunk.ID
+			}
+
+			if chunk.More {
+				if ctx.Err() != nil {
+					pw.CloseWithError(ctx.Err())
+					return
+				}
+				continue
+			}
+
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				pw.CloseWithError(ctx.Err())
+				return
+			}
+		}
+	}()
+
+	return &serialReader{
+		PipeReader: pr,
+		cancel:     cancel,
+	}, nil
+}
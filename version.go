@@ -12,6 +12,7 @@ import (
 type Version struct {
 	app               *App
 	id                string
+	tag               string
 	uploadedTimeplate time.Time
 }
 
@@ -25,6 +26,10 @@ type VersionList struct {
 type VersionListItem struct {
 	Cursor  string
 	Version Version
+
+	// config is the version's package configuration, if it was requested
+	// with the list.
+	config *objects.PackageConfig
 }
 
 // ID ..
@@ -32,6 +37,11 @@ func (v *Version) ID() string {
 	return v.id
 }
 
+// App returns the App this Version belongs to.
+func (v *Version) App() *App {
+	return v.app
+}
+
 // UploadedTime ..
 func (v *Version) UploadedTime() time.Time {
 	return v.uploadedTimeplate
@@ -103,6 +113,49 @@ func (v *Version) Icon() (*objects.PackageFragment, error) {
 	return &resp.Bucket.App.Version.Icon, nil
 }
 
+// Info fetches the package information of the version, including the
+// metadata from its configuration such as author and summary.
+func (v *Version) Info() (*objects.PackageInfo, error) {
+
+	req := v.app.bucket.r.newRequest(fmt.Sprintf(`
+                query {
+                        bucket(name: "%s") {
+                                app(name: "%s") {
+                                        version(ref: "%s") {
+                                                info {
+                                                        id
+                                                        timestamp
+                                                        configurationDetails {
+                                                                info {
+                                                                        app
+                                                                        author
+                                                                        description
+                                                                        summary
+                                                                        url
+                                                                        version
+                                                                        kernel
+                                                                }
+                                                        }
+                                                }
+                                        }
+                                }
+                        }
+                }
+        `, v.app.bucket.Name(), v.app.Name(), v.ID()))
+
+	type responseContainer struct {
+		Bucket objects.Bucket `json:"bucket"`
+	}
+
+	resp := new(responseContainer)
+	err := v.app.bucket.r.mgr.c.graphql.Run(v.app.bucket.r.mgr.c.ctx, req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp.Bucket.App.Version.Info, nil
+}
+
 // Tag ..
 func (v *Version) Tag() (string, error) {
 
@@ -198,6 +251,6 @@ func (v *Version) Delete() error {
 // Germ returns a string that can be used to identify this app/version. This can
 // be used in operations such as build, or run.
 func (v *Version) Germ() string {
-	return fmt.Sprintf("%s:%s/%s/%s", v.app.bucket.r.name, v.app.bucket.Name(),
-		v.app.Name(), v.ID())
+	return VersionGerm(v.app.bucket.r.name, v.app.bucket.Name(), v.app.Name(),
+		v.ID()).String()
 }
@@ -1,7 +1,11 @@
 package goapi
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 
 	"github.com/sisatech/goapi/pkg/objects"
 )
@@ -23,6 +27,7 @@ type VirtualMachineList struct {
 type VirtualMachineListItem struct {
 	Cursor         string
 	VirtualMachine VirtualMachine
+	Details        *VirtualMachineDetails
 }
 
 // ID ..
@@ -52,11 +57,143 @@ func (v *VirtualMachine) Delete() error {
 	return v.mgr.c.graphql.Run(v.mgr.c.ctx, req, &resp)
 }
 
-// Image downloads the virtual machine disk image.
-func (v *VirtualMachine) Image() error {
+// ImageArguments contain the fields used when capturing the disk image of a
+// virtual machine.
+type ImageArguments struct {
+
+	// Pause causes the virtual machine to be paused before its disk is
+	// captured, so that the image is consistent. The machine is left
+	// paused afterwards.
+	Pause bool
+
+	// DiskFormat is the desired format of the captured disk image. If left
+	// empty the environment's native format is used.
+	DiskFormat DiskFormat
+}
+
+// ImagePushArguments contain the fields used when capturing the disk image of
+// a virtual machine as a new version of an app.
+type ImagePushArguments struct {
+	ImageArguments
+
+	// DestinationBucket and DestinationApp identify the app the image is
+	// pushed to, and are required.
+	DestinationBucket string
+	DestinationApp    string
+
+	// RepositoryName is the repository the image is pushed to. If left
+	// empty the local repository is used.
+	RepositoryName string
+}
+
+// imageURL returns the location the virtual machine disk image can be
+// downloaded from, pausing the machine first if requested.
+func (v *VirtualMachine) imageURL(ctx context.Context, args *ImageArguments) (string, error) {
+
+	if args.Pause {
+		err := v.Pause()
+		if err != nil {
+			return "", fmt.Errorf("failed to pause virtual machine: %v", err)
+		}
+		_, err = v.WaitForState(ctx, VMStatePaused)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	req := v.mgr.environment.newRequest(fmt.Sprintf(`
+		query {
+			vm(id: "%s") {
+				download
+			}
+		}
+	`, v.ID()))
+
+	type responseContainer struct {
+		VM objects.VM `json:"vm"`
+	}
+
+	resp := new(responseContainer)
+	err := v.mgr.c.graphql.Run(ctx, req, &resp)
+	if err != nil {
+		return "", err
+	}
+	if resp.VM.Download == "" {
+		return "", fmt.Errorf("virtual machine '%s' has no downloadable disk", v.ID())
+	}
+
+	u, err := url.Parse(fmt.Sprintf("%s%s", v.mgr.environment.host, resp.VM.Download))
+	if err != nil {
+		return "", err
+	}
+	if args.DiskFormat != "" {
+		q := u.Query()
+		q.Set("format", string(args.DiskFormat))
+		u.RawQuery = q.Encode()
+	}
+
+	return u.String(), nil
+}
+
+// Image downloads the virtual machine disk image, writing it to w. The context
+// bounds the whole download, including waiting for the machine to pause.
+func (v *VirtualMachine) Image(ctx context.Context, w io.Writer, args *ImageArguments) error {
+
+	if args == nil {
+		args = new(ImageArguments)
+	}
+
+	u, err := v.imageURL(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := v.mgr.c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned non-200 status code: %v - %s",
+			resp.StatusCode, resp.Status)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// PushImage captures the virtual machine disk image and pushes it as a new
+// version of an app. The push is performed by the machine's environment, so
+// the image never passes through the caller. The context only bounds preparing
+// the image, including waiting for the machine to pause, and not the push.
+func (v *VirtualMachine) PushImage(ctx context.Context, args *ImagePushArguments) (*PushOperation, error) {
+
+	if args == nil || args.DestinationBucket == "" || args.DestinationApp == "" {
+		return nil, fmt.Errorf("a destination bucket and app are required to push an image")
+	}
+
+	u, err := v.imageURL(ctx, &args.ImageArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.mgr.environment.Push(&PushArguments{
+		Germ:              URLGerm(u).String(),
+		DestinationBucket: args.DestinationBucket,
+		DestinationApp:    args.DestinationApp,
+		RepositoryName:    args.RepositoryName,
+	})
+}
+
 // Pause the virtual machine.
 func (v *VirtualMachine) Pause() error {
 	req := v.mgr.environment.newRequest(fmt.Sprintf(`
@@ -111,7 +248,7 @@ func (v *VirtualMachine) Start() error {
 }
 
 // Status returns the state of the virtual machine.
-func (v *VirtualMachine) Status() (string, error) {
+func (v *VirtualMachine) Status() (VMState, error) {
 
 	req := v.mgr.environment.newRequest(fmt.Sprintf(`
 		query {
@@ -131,11 +268,5 @@ func (v *VirtualMachine) Status() (string, error) {
 		return "", err
 	}
 
-	return resp.VM.Status, nil
-}
-
-// Tail the virtual machine serial output.
-func (v *VirtualMachine) Tail() error {
-	// TODO implement when log-streaming is available
-	return nil
+	return VMState(resp.VM.Status), nil
 }
@@ -0,0 +1,251 @@
+package goapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/sisatech/goapi/pkg/graphqlws"
+	"github.com/sisatech/goapi/pkg/objects"
+)
+
+// MachineEventType identifies the kind of change described by a MachineEvent.
+type MachineEventType string
+
+const (
+	// MachineCreated reports a machine that didn't exist before.
+	MachineCreated = MachineEventType("created")
+	// MachineStateChanged reports a machine whose state has changed.
+	MachineStateChanged = MachineEventType("state-changed")
+	// MachineDeleted reports a machine that no longer exists.
+	MachineDeleted = MachineEventType("deleted")
+	// MachineNetworkUpdated reports a change to a machine's network
+	// interfaces, routes or redirects.
+	MachineNetworkUpdated = MachineEventType("network-updated")
+	// MachineWatchError reports a problem with the watch itself, in Err.
+	MachineWatchError = MachineEventType("error")
+)
+
+// MachineEvent describes a change to a virtual machine observed by
+// MachinesManager.Watch. Details contains the summary and network fields of
+// the machine after the change, or before it for MachineDeleted events. Events
+// of type MachineWatchError carry an error in Err and nothing else.
+type MachineEvent struct {
+	Type          MachineEventType
+	ID            string
+	Name          string
+	State         VMState
+	PreviousState VMState
+	Details       *VirtualMachineDetails
+	Err           error
+}
+
+// WatchFilter restricts the machines reported by MachinesManager.Watch. A nil
+// or zero WatchFilter matches every machine.
+type WatchFilter struct {
+
+	// IDs limits events to machines with these IDs.
+	IDs []string
+
+	// Name limits events to machines with names matching this pattern, as
+	// understood by path.Match.
+	Name string
+}
+
+func (f *WatchFilter) matches(d *VirtualMachineDetails) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.IDs) != 0 {
+		var found bool
+		for _, id := range f.IDs {
+			if id == d.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return matches(f.Name, d.Name)
+}
+
+// watchBuffer is the number of events, and of machine snapshots, buffered by
+// Watch for a consumer that has fallen behind.
+const watchBuffer = 64
+
+// Watch subscribes to changes to the machines of the environment and reports
+// them as events on the returned channel until the context ends or the
+// subscription is terminated, after which the channel is closed. Machines that
+// exist when Watch is called don't produce events until they change.
+//
+// Callers must keep draining the channel. Events are computed from snapshots
+// of every machine, and while the channel is full the oldest unprocessed
+// snapshots are discarded in favour of newer ones, so a slow consumer never
+// holds up the client's other subscriptions. Changes that are undone before a
+// snapshot is processed are therefore not reported.
+func (m *MachinesManager) Watch(ctx context.Context, filter *WatchFilter) (<-chan MachineEvent, error) {
+
+	if m.environment != m.c.reposMgr.Local {
+		return nil, fmt.Errorf("watching machines is only supported for the local environment")
+	}
+
+	if filter != nil {
+		if _, err := path.Match(filter.Name, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern '%s': %v", filter.Name, err)
+		}
+	}
+
+	groups := []VMFieldGroup{VMFieldsSummary, VMFieldsNetwork}
+	selection, err := vmSelection(groups...)
+	if err != nil {
+		return nil, err
+	}
+
+	type responseContainer struct {
+		ListMachines objects.VMsConnection `json:"listMachines"`
+	}
+
+	events, err := m.c.subscriptions.SubscribeInto(ctx, fmt.Sprintf(`
+		subscription {
+			listMachines {
+				edges {
+					node {
+						%s
+					}
+				}
+			}
+		}`, selection), nil, func() interface{} {
+		return new(responseContainer)
+	}, &graphqlws.SubscribeOptions{
+		Buffer: watchBuffer,
+		// each value is a complete snapshot, so only the latest matters
+		Overflow: graphqlws.OverflowDropOldest,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan MachineEvent, watchBuffer)
+
+	emit := func(e MachineEvent) {
+		select {
+		case out <- e:
+		case <-ctx.Done():
+		}
+	}
+
+	go func() {
+		defer close(out)
+
+		var previous map[string]*VirtualMachineDetails
+		for event := range events {
+
+			if event.Err != nil {
+				if errs, ok := event.Err.(graphqlws.GQLErrors); ok {
+					for _, e := range errs {
+						emit(MachineEvent{Type: MachineWatchError, Err: fmt.Errorf("%s", e.Message)})
+					}
+				} else {
+					emit(MachineEvent{Type: MachineWatchError, Err: event.Err})
+				}
+			}
+			if event.Value == nil {
+				continue
+			}
+			resp := event.Value.(*responseContainer)
+
+			current := make(map[string]*VirtualMachineDetails)
+			for i := range resp.ListMachines.Edges {
+				d, err := newVirtualMachineDetails(&resp.ListMachines.Edges[i].Node, groups)
+				if err != nil {
+					emit(MachineEvent{Type: MachineWatchError, Err: err})
+					continue
+				}
+				if filter.matches(d) {
+					current[d.ID] = d
+				}
+			}
+
+			if previous != nil {
+				for _, e := range diffMachines(previous, current) {
+					emit(e)
+				}
+			}
+			previous = current
+		}
+	}()
+
+	return out, nil
+}
+
+// diffMachines compares two snapshots of machines and returns the events that
+// describe the changes between them.
+func diffMachines(previous, current map[string]*VirtualMachineDetails) []MachineEvent {
+
+	out := make([]MachineEvent, 0)
+
+	for id, d := range current {
+		p, ok := previous[id]
+		if !ok {
+			out = append(out, MachineEvent{
+				Type:    MachineCreated,
+				ID:      id,
+				Name:    d.Name,
+				State:   d.Status,
+				Details: d,
+			})
+			continue
+		}
+
+		if p.Status != d.Status {
+			out = append(out, MachineEvent{
+				Type:          MachineStateChanged,
+				ID:            id,
+				Name:          d.Name,
+				State:         d.Status,
+				PreviousState: p.Status,
+				Details:       d,
+			})
+		}
+
+		if !networksEqual(p, d) {
+			out = append(out, MachineEvent{
+				Type:    MachineNetworkUpdated,
+				ID:      id,
+				Name:    d.Name,
+				State:   d.Status,
+				Details: d,
+			})
+		}
+	}
+
+	for id, p := range previous {
+		if _, ok := current[id]; !ok {
+			out = append(out, MachineEvent{
+				Type:          MachineDeleted,
+				ID:            id,
+				Name:          p.Name,
+				State:         VMStateDeleted,
+				PreviousState: p.Status,
+				Details:       p,
+			})
+		}
+	}
+
+	return out
+}
+
+func networksEqual(a, b *VirtualMachineDetails) bool {
+	x, err := json.Marshal([]interface{}{a.Networks, a.Redirects})
+	if err != nil {
+		return false
+	}
+	y, err := json.Marshal([]interface{}{b.Networks, b.Redirects})
+	if err != nil {
+		return false
+	}
+	return string(x) == string(y)
+}
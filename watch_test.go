@@ -0,0 +1,110 @@
+package goapi
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestDiffMachines(t *testing.T) {
+
+	machine := func(id string, state VMState, ip string) *VirtualMachineDetails {
+		return &VirtualMachineDetails{
+			Name:     id + "-name",
+			Status:   state,
+			Networks: []VMNetworkDetails{{Name: "eth0", IP: ip}},
+		}
+	}
+
+	type event struct {
+		id       string
+		typ      MachineEventType
+		state    VMState
+		previous VMState
+	}
+
+	tests := []struct {
+		name     string
+		previous map[string]*VirtualMachineDetails
+		current  map[string]*VirtualMachineDetails
+		want     []event
+	}{
+		{
+			name:    "created",
+			current: map[string]*VirtualMachineDetails{"a": machine("a", VMStateCreating, "")},
+			want:    []event{{"a", MachineCreated, VMStateCreating, ""}},
+		},
+		{
+			name:     "unchanged",
+			previous: map[string]*VirtualMachineDetails{"a": machine("a", VMStateAlive, "10.0.0.2")},
+			current:  map[string]*VirtualMachineDetails{"a": machine("a", VMStateAlive, "10.0.0.2")},
+		},
+		{
+			name:     "state changed",
+			previous: map[string]*VirtualMachineDetails{"a": machine("a", VMStateBooting, "10.0.0.2")},
+			current:  map[string]*VirtualMachineDetails{"a": machine("a", VMStateAlive, "10.0.0.2")},
+			want:     []event{{"a", MachineStateChanged, VMStateAlive, VMStateBooting}},
+		},
+		{
+			name:     "network updated",
+			previous: map[string]*VirtualMachineDetails{"a": machine("a", VMStateAlive, "")},
+			current:  map[string]*VirtualMachineDetails{"a": machine("a", VMStateAlive, "10.0.0.2")},
+			want:     []event{{"a", MachineNetworkUpdated, VMStateAlive, ""}},
+		},
+		{
+			name:     "state and network changed",
+			previous: map[string]*VirtualMachineDetails{"a": machine("a", VMStateBooting, "")},
+			current:  map[string]*VirtualMachineDetails{"a": machine("a", VMStateAlive, "10.0.0.2")},
+			want: []event{
+				{"a", MachineNetworkUpdated, VMStateAlive, ""},
+				{"a", MachineStateChanged, VMStateAlive, VMStateBooting},
+			},
+		},
+		{
+			name:     "deleted",
+			previous: map[string]*VirtualMachineDetails{"a": machine("a", VMStatePaused, "")},
+			current:  map[string]*VirtualMachineDetails{},
+			want:     []event{{"a", MachineDeleted, VMStateDeleted, VMStatePaused}},
+		},
+		{
+			name: "mixed",
+			previous: map[string]*VirtualMachineDetails{
+				"a": machine("a", VMStateAlive, ""),
+				"b": machine("b", VMStateAlive, ""),
+			},
+			current: map[string]*VirtualMachineDetails{
+				"b": machine("b", VMStateBroken, ""),
+				"c": machine("c", VMStateCreating, ""),
+			},
+			want: []event{
+				{"a", MachineDeleted, VMStateDeleted, VMStateAlive},
+				{"b", MachineStateChanged, VMStateBroken, VMStateAlive},
+				{"c", MachineCreated, VMStateCreating, ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		events := diffMachines(tt.previous, tt.current)
+		sort.Slice(events, func(i, j int) bool {
+			if events[i].ID != events[j].ID {
+				return events[i].ID < events[j].ID
+			}
+			return events[i].Type < events[j].Type
+		})
+
+		if len(events) != len(tt.want) {
+			t.Errorf("%s: got %d events, want %d: %+v", tt.name, len(events), len(tt.want), events)
+			continue
+		}
+		for i, e := range events {
+			w := tt.want[i]
+			if e.ID != w.id || e.Type != w.typ || e.State != w.state || e.PreviousState != w.previous {
+				t.Errorf("%s: event %d = %+v, want %+v", tt.name, i, e, w)
+			}
+			if e.Name != w.id+"-name" || e.Details == nil {
+				t.Errorf("%s: event %d missing name or details: %+v", tt.name, i, e)
+			}
+		}
+	}
+
+}